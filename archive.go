@@ -0,0 +1,72 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-audio/audio"
+)
+
+// isArchive 判断输入是否为按扩展名识别的 zip 压缩包
+func isArchive(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".zip")
+}
+
+// archiveInputs 列出 zip 中所有 .wav 条目，按条目名排序后作为输入
+func archiveInputs(path string) ([]clipSpec, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var names []string
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(f.Name), ".wav") {
+			continue
+		}
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	specs := make([]clipSpec, 0, len(names))
+	for _, name := range names {
+		specs = append(specs, clipSpec{path: path + ":" + name, archive: path, entry: name})
+	}
+	return specs, nil
+}
+
+// decodeArchiveEntry 从 zip 中读出条目并在内存中解码
+func decodeArchiveEntry(archive, entry string) (*audio.IntBuffer, error) {
+	data, err := readArchiveEntry(archive, entry)
+	if err != nil {
+		return nil, err
+	}
+	return decodeWAVReader(bytes.NewReader(data), archive+":"+entry)
+}
+
+// readArchiveEntry 读出 zip 中条目的全部内容
+func readArchiveEntry(archive, entry string) ([]byte, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name != entry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return ioutil.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s 中没有条目 %s", archive, entry)
+}
@@ -0,0 +1,48 @@
+package main
+
+import "github.com/go-audio/audio"
+
+// convertBitDepth 将 buf 中的采样值缩放到 bits 位深并原地更新 SourceBitDepth。
+// 8 位 WAV 采样为无符号数（go-audio 解码后范围 0..255），换算时先转为有符号再处理。
+func convertBitDepth(buf *audio.IntBuffer, bits int) {
+	from := buf.SourceBitDepth
+	if from == bits || from == 0 {
+		buf.SourceBitDepth = bits
+		return
+	}
+	maxVal := 1<<(bits-1) - 1
+	minVal := -(1 << (bits - 1))
+	for i, v := range buf.Data {
+		if from == 8 {
+			v -= 128
+		}
+		if bits > from {
+			v <<= bits - from
+		} else {
+			// 降位深时四舍五入，并钳制到目标范围防止溢出
+			shift := from - bits
+			v = (v + 1<<(shift-1)) >> shift
+			if v > maxVal {
+				v = maxVal
+			} else if v < minVal {
+				v = minVal
+			}
+		}
+		if bits == 8 {
+			v += 128
+		}
+		buf.Data[i] = v
+	}
+	buf.SourceBitDepth = bits
+}
+
+// silenceFrames 返回 n 帧静音采样；8 位 WAV 为无符号数，静音值是 128
+func silenceFrames(n, ch, bits int) []int {
+	data := make([]int, n*ch)
+	if bits == 8 {
+		for i := range data {
+			data[i] = 128
+		}
+	}
+	return data
+}
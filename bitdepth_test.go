@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-audio/audio"
+)
+
+func TestConvertBitDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		in, want []int
+	}{
+		// 8 位无符号：128 为零点，升位深前先减去偏移
+		{"8→16", 8, 16, []int{0, 128, 255, 129}, []int{-32768, 0, 32512, 256}},
+		{"16→8", 16, 8, []int{-32768, 0, 32767, 256, -256}, []int{0, 128, 255, 129, 127}},
+		// 16→8 时 32767 舍入后为 128，钳制到 127 再加偏移
+		{"16→8 钳制", 16, 8, []int{32767, 32640, 32639}, []int{255, 255, 255}},
+		{"16→24", 16, 24, []int{-32768, -1, 0, 1, 32767}, []int{-8388608, -256, 0, 256, 8388352}},
+		{"24→16", 24, 16, []int{-8388608, -256, 0, 256, 8388352}, []int{-32768, -1, 0, 1, 32767}},
+		// 降位深四舍五入：低 8 位达到 0x80 时进位
+		{"24→16 舍入", 24, 16, []int{127, 128, 383, 384, -128, -129}, []int{0, 1, 1, 2, 0, -1}},
+		// 接近满幅的值舍入后超出 16 位范围，被钳制
+		{"24→16 钳制", 24, 16, []int{8388607, 8388480, 8388479}, []int{32767, 32767, 32767}},
+		{"同位深", 16, 16, []int{-5, 0, 5}, []int{-5, 0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &audio.IntBuffer{
+				Format:         &audio.Format{NumChannels: 1, SampleRate: 44100},
+				Data:           append([]int(nil), tt.in...),
+				SourceBitDepth: tt.from,
+			}
+			convertBitDepth(buf, tt.to)
+			if !reflect.DeepEqual(buf.Data, tt.want) {
+				t.Errorf("convertBitDepth(%v, %d→%d) = %v, want %v", tt.in, tt.from, tt.to, buf.Data, tt.want)
+			}
+			if buf.SourceBitDepth != tt.to {
+				t.Errorf("SourceBitDepth = %d, want %d", buf.SourceBitDepth, tt.to)
+			}
+		})
+	}
+}
+
+func TestSilenceFrames(t *testing.T) {
+	if got := silenceFrames(2, 2, 8); !reflect.DeepEqual(got, []int{128, 128, 128, 128}) {
+		t.Errorf("8 位静音 = %v", got)
+	}
+	if got := silenceFrames(2, 1, 16); !reflect.DeepEqual(got, []int{0, 0}) {
+		t.Errorf("16 位静音 = %v", got)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-audio/audio"
+)
+
+// downmixToMono 把多声道交错采样按帧取各声道平均值合成单声道，
+// 末尾不足一帧的多余采样被丢弃
+func downmixToMono(buf *audio.IntBuffer) {
+	ch := buf.Format.NumChannels
+	if ch <= 1 {
+		return
+	}
+	frames := len(buf.Data) / ch
+	mono := make([]int, frames)
+	for i := 0; i < frames; i++ {
+		sum := 0
+		for _, v := range buf.Data[i*ch : (i+1)*ch] {
+			sum += v
+		}
+		mono[i] = sum / ch
+	}
+	buf.Data = mono
+	buf.Format = &audio.Format{NumChannels: 1, SampleRate: buf.Format.SampleRate}
+}
+
+// monoToStereo 把单声道采样原样复制到左右两个声道，其他声道数的 buf 不变
+func monoToStereo(buf *audio.IntBuffer) {
+	if buf.Format.NumChannels != 1 {
+		return
+	}
+	stereo := make([]int, 2*len(buf.Data))
+	for i, v := range buf.Data {
+		stereo[2*i] = v
+		stereo[2*i+1] = v
+	}
+	buf.Data = stereo
+	buf.Format = &audio.Format{NumChannels: 2, SampleRate: buf.Format.SampleRate}
+}
+
+// alignFrames 检查采样数是否为声道数的整数倍，避免拼接后声道错位。
+// mode 为 "pad" 时用静音补齐最后一帧，为 "error" 时返回错误。
+func alignFrames(buf *audio.IntBuffer, mode string) error {
+	ch := buf.Format.NumChannels
+	rem := len(buf.Data) % ch
+	if rem == 0 {
+		return nil
+	}
+	if mode != "pad" {
+		return fmt.Errorf("采样数 %d 不是声道数 %d 的整数倍", len(buf.Data), ch)
+	}
+	buf.Data = append(buf.Data, silenceFrames(1, ch, buf.SourceBitDepth)[:ch-rem]...)
+	return nil
+}
+
+// ituCenter 是 ITU-R BS.775 下混中中置与环绕声道的系数（-3dB）
+const ituCenter = 0.7071067811865476
+
+// speakerToStereo 是 WAVE_FORMAT_EXTENSIBLE 声道掩码中各扬声器位下混到左右声道的系数，
+// 声道在数据中按掩码位从低到高排列。LFE 不参与下混
+var speakerToStereo = map[uint32][2]float64{
+	0x1:     {1, 0},                 // FL
+	0x2:     {0, 1},                 // FR
+	0x4:     {ituCenter, ituCenter}, // FC
+	0x8:     {0, 0},                 // LFE
+	0x10:    {ituCenter, 0},         // BL
+	0x20:    {0, ituCenter},         // BR
+	0x40:    {ituCenter, 0},         // FLC
+	0x80:    {0, ituCenter},         // FRC
+	0x100:   {0.5, 0.5},             // BC
+	0x200:   {ituCenter, 0},         // SL
+	0x400:   {0, ituCenter},         // SR
+	0x800:   {0.5, 0.5},             // TC
+	0x1000:  {ituCenter, 0},         // TFL
+	0x2000:  {0.5, 0.5},             // TFC
+	0x4000:  {0, ituCenter},         // TFR
+	0x8000:  {ituCenter, 0},         // TBL
+	0x10000: {0.5, 0.5},             // TBC
+	0x20000: {0, ituCenter},         // TBR
+}
+
+// defaultChannelMask 是没有声道掩码时按 WAV 默认顺序假定的布局：
+// 5.1 为 L R C LFE Lb Rb，7.1 为 L R C LFE Lb Rb Ls Rs
+var defaultChannelMask = map[int]uint32{
+	6: 0x3F,
+	8: 0x63F,
+}
+
+// downmixCoefficients 按声道掩码返回每个输入声道下混到左右声道的系数；
+// mask 为 0 时使用 defaultChannelMask
+func downmixCoefficients(ch int, mask uint32) ([][2]float64, error) {
+	if mask == 0 {
+		mask = defaultChannelMask[ch]
+		if mask == 0 {
+			return nil, fmt.Errorf("%d 声道输入没有声道掩码，仅能按默认布局下混 5.1（6 声道）和 7.1（8 声道）", ch)
+		}
+	}
+	var coeffs [][2]float64
+	for bit := uint32(1); bit != 0 && len(coeffs) < ch; bit <<= 1 {
+		if mask&bit == 0 {
+			continue
+		}
+		k, ok := speakerToStereo[bit]
+		if !ok {
+			return nil, fmt.Errorf("不支持声道掩码 0x%X 中的扬声器位 0x%X", mask, bit)
+		}
+		coeffs = append(coeffs, k)
+	}
+	if len(coeffs) != ch {
+		return nil, fmt.Errorf("声道掩码 0x%X 只描述了 %d 个声道，输入有 %d 个", mask, len(coeffs), ch)
+	}
+	return coeffs, nil
+}
+
+// surroundDownmix 把多声道输入按 ITU 系数下混为 outCh（1 或 2）声道，声道布局
+// 取自 mask（见 downmixCoefficients）；单声道取左右声道的平均值；超出位深范围的值被钳制
+func surroundDownmix(buf *audio.IntBuffer, outCh int, mask uint32) error {
+	ch := buf.Format.NumChannels
+	coeffs, err := downmixCoefficients(ch, mask)
+	if err != nil {
+		return err
+	}
+	bits := buf.SourceBitDepth
+	maxVal := float64(int(1)<<(bits-1) - 1)
+	minVal := -float64(int(1) << (bits - 1))
+	offset := 0
+	if bits == 8 {
+		offset = 128
+	}
+	clamp := func(f float64) int {
+		f = math.Round(f)
+		if f > maxVal {
+			f = maxVal
+		} else if f < minVal {
+			f = minVal
+		}
+		return int(f) + offset
+	}
+
+	frames := len(buf.Data) / ch
+	out := make([]int, 0, frames*outCh)
+	for i := 0; i < frames; i++ {
+		var lr [2]float64
+		for c, k := range coeffs {
+			v := float64(buf.Data[i*ch+c] - offset)
+			lr[0] += v * k[0]
+			lr[1] += v * k[1]
+		}
+		if outCh == 1 {
+			out = append(out, clamp((lr[0]+lr[1])/2))
+		} else {
+			out = append(out, clamp(lr[0]), clamp(lr[1]))
+		}
+	}
+	buf.Data = out
+	buf.Format = &audio.Format{NumChannels: outCh, SampleRate: buf.Format.SampleRate}
+	return nil
+}
+
+// looksPlanar 判断双声道 buf 是否像是把按声道平铺（planar）的数据当作交错数据读出：
+// 此时两个“声道”其实是同一信号的相邻采样，左右之差与右声道到下一帧左声道之差
+// 都约为左声道帧间差的一半；而真实的立体声要么左右几乎相同，要么差异远大于此
+func looksPlanar(buf *audio.IntBuffer) bool {
+	if buf.Format.NumChannels != 2 {
+		return false
+	}
+	frames := len(buf.Data) / 2
+	var lr, rl, ll float64
+	for i := 0; i+1 < frames; i++ {
+		l, r, next := buf.Data[2*i], buf.Data[2*i+1], buf.Data[2*i+2]
+		lr += math.Abs(float64(r - l))
+		rl += math.Abs(float64(next - r))
+		ll += math.Abs(float64(next - l))
+	}
+	if ll == 0 {
+		return false
+	}
+	in := func(x float64) bool { return x > 0.35 && x < 0.65 }
+	return in(lr/ll) && in(rl/ll)
+}
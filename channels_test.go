@@ -0,0 +1,155 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-audio/audio"
+)
+
+// stereoBuffer 返回以 data 为交错采样的双声道缓冲
+func stereoBuffer(bits int, data ...int) *audio.IntBuffer {
+	return &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 2, SampleRate: 44100},
+		Data:           data,
+		SourceBitDepth: bits,
+	}
+}
+
+func TestDownmixToMono(t *testing.T) {
+	tests := []struct {
+		name     string
+		in, want []int
+	}{
+		// 逐帧手算 (L+R)/2，结果向零取整
+		{"平均", []int{100, 300, -100, -300, 32767, 32767, 32767, -32768, 1, 2, -1, -2}, []int{200, -200, 32767, 0, 1, -1}},
+		// 奇数个采样：末尾不完整的帧被丢弃
+		{"奇数采样", []int{10, 20, 30, 40, 50}, []int{15, 35}},
+		{"只有半帧", []int{7}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := stereoBuffer(16, tt.in...)
+			downmixToMono(buf)
+			if !reflect.DeepEqual(buf.Data, tt.want) {
+				t.Errorf("downmixToMono(%v) = %v, want %v", tt.in, buf.Data, tt.want)
+			}
+			if buf.Format.NumChannels != 1 || buf.Format.SampleRate != 44100 {
+				t.Errorf("Format = %+v", *buf.Format)
+			}
+		})
+	}
+}
+
+func TestAlignFrames(t *testing.T) {
+	buf := stereoBuffer(16, 1, 2, 3)
+	if err := alignFrames(buf, "error"); err == nil {
+		t.Error("奇数采样应返回错误")
+	}
+	if err := alignFrames(buf, "pad"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 0}; !reflect.DeepEqual(buf.Data, want) {
+		t.Errorf("pad 后 = %v, want %v", buf.Data, want)
+	}
+}
+
+func TestSurroundDownmix51(t *testing.T) {
+	// 默认 5.1 布局 L R C LFE Lb Rb：L = FL + 0.707·C + 0.707·Lb，R = FR + 0.707·C + 0.707·Rb，LFE 丢弃
+	frames := []int{
+		1000, 2000, 1000, 5000, 2000, 0,
+		0, 0, 0, 32767, 0, 0,
+		30000, 30000, 30000, 0, 30000, 30000,
+		-30000, -30000, -30000, 0, -30000, -30000,
+	}
+	tests := []struct {
+		outCh int
+		want  []int
+	}{
+		{2, []int{3121, 2707, 0, 0, 32767, 32767, -32768, -32768}},
+		{1, []int{2914, 0, 32767, -32768}},
+	}
+	for _, tt := range tests {
+		buf := &audio.IntBuffer{
+			Format:         &audio.Format{NumChannels: 6, SampleRate: 48000},
+			Data:           append([]int(nil), frames...),
+			SourceBitDepth: 16,
+		}
+		if err := surroundDownmix(buf, tt.outCh, 0); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(buf.Data, tt.want) {
+			t.Errorf("下混为 %d 声道 = %v, want %v", tt.outCh, buf.Data, tt.want)
+		}
+		if buf.Format.NumChannels != tt.outCh || buf.Format.SampleRate != 48000 {
+			t.Errorf("Format = %+v", *buf.Format)
+		}
+	}
+}
+
+func TestSurroundDownmixUnknownLayout(t *testing.T) {
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 4, SampleRate: 48000},
+		Data:           []int{0, 0, 0, 0},
+		SourceBitDepth: 16,
+	}
+	if err := surroundDownmix(buf, 2, 0); err == nil {
+		t.Error("没有声道掩码的 4 声道输入应返回错误")
+	}
+}
+
+func TestMonoToStereo(t *testing.T) {
+	buf := monoBuffer(16, 100, -200, 32767, -32768)
+	monoToStereo(buf)
+	if buf.Format.NumChannels != 2 || buf.Format.SampleRate != 44100 {
+		t.Fatalf("Format = %+v", *buf.Format)
+	}
+	if len(buf.Data) != 8 {
+		t.Fatalf("len = %d, want 8", len(buf.Data))
+	}
+	// 每帧左右声道相同，且等于原单声道采样
+	for i, v := range []int{100, -200, 32767, -32768} {
+		if l, r := buf.Data[2*i], buf.Data[2*i+1]; l != r || l != v {
+			t.Errorf("帧 %d: L=%d R=%d, want %d", i, l, r, v)
+		}
+	}
+}
+
+func TestMonoToStereoLeavesStereo(t *testing.T) {
+	buf := stereoBuffer(16, 1, 2)
+	monoToStereo(buf)
+	if !reflect.DeepEqual(buf.Data, []int{1, 2}) || buf.Format.NumChannels != 2 {
+		t.Errorf("立体声输入被修改: %v", buf.Data)
+	}
+}
+
+func TestChannelConversionBothDirections(t *testing.T) {
+	// -channels 1：立体声逐帧取平均
+	stereo := stereoBuffer(16, 1000, 3000, -500, 500, 32767, 32767)
+	downmixToMono(stereo)
+	if want := []int{2000, 0, 32767}; !reflect.DeepEqual(stereo.Data, want) || stereo.Format.NumChannels != 1 {
+		t.Errorf("立体声→单声道 = %v (%d 声道), want %v", stereo.Data, stereo.Format.NumChannels, want)
+	}
+
+	// -channels 2：单声道复制到左右声道
+	mono := monoBuffer(24, 8388607, -8388608, 0)
+	monoToStereo(mono)
+	if want := []int{8388607, 8388607, -8388608, -8388608, 0, 0}; !reflect.DeepEqual(mono.Data, want) || mono.Format.NumChannels != 2 {
+		t.Errorf("单声道→立体声 = %v (%d 声道), want %v", mono.Data, mono.Format.NumChannels, want)
+	}
+
+	// 单声道→立体声→单声道还原原始采样
+	round := monoBuffer(16, 5, -7, 32767)
+	monoToStereo(round)
+	downmixToMono(round)
+	if want := []int{5, -7, 32767}; !reflect.DeepEqual(round.Data, want) {
+		t.Errorf("往返转换 = %v, want %v", round.Data, want)
+	}
+
+	// 目标声道数与输入相同时不做任何处理
+	same := monoBuffer(16, 1, 2)
+	downmixToMono(same)
+	if !reflect.DeepEqual(same.Data, []int{1, 2}) {
+		t.Errorf("单声道输入被修改: %v", same.Data)
+	}
+}
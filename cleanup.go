@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// 运行期间登记的临时文件、未完成的输出文件和 ffmpeg 子进程，
+// 出错或收到中断信号退出前统一清理
+var cleanup = struct {
+	sync.Mutex
+	temps   map[string]bool
+	outputs map[string]bool
+	procs   map[*os.Process]bool
+}{
+	temps:   make(map[string]bool),
+	outputs: make(map[string]bool),
+	procs:   make(map[*os.Process]bool),
+}
+
+// addTemp 登记临时文件，无论成功与否退出前都会删除
+func addTemp(path string) {
+	cleanup.Lock()
+	cleanup.temps[path] = true
+	cleanup.Unlock()
+}
+
+// removeTemp 立即删除临时文件并取消登记
+func removeTemp(path string) {
+	cleanup.Lock()
+	delete(cleanup.temps, path)
+	cleanup.Unlock()
+	os.Remove(path)
+}
+
+// addOutput 登记正在生成的输出文件，异常退出时删除以免留下残缺结果
+func addOutput(path string) {
+	cleanup.Lock()
+	cleanup.outputs[path] = true
+	cleanup.Unlock()
+}
+
+func trackProcess(p *os.Process) {
+	cleanup.Lock()
+	cleanup.procs[p] = true
+	cleanup.Unlock()
+}
+
+func untrackProcess(p *os.Process) {
+	cleanup.Lock()
+	delete(cleanup.procs, p)
+	cleanup.Unlock()
+}
+
+// abortCleanup 终止仍在运行的 ffmpeg，删除临时文件和未完成的输出
+func abortCleanup() {
+	cleanup.Lock()
+	defer cleanup.Unlock()
+	for p := range cleanup.procs {
+		p.Kill()
+	}
+	for path := range cleanup.temps {
+		os.Remove(path)
+	}
+	for path := range cleanup.outputs {
+		os.Remove(path)
+	}
+}
+
+// finishCleanup 在成功结束时删除临时文件，保留全部输出
+func finishCleanup() {
+	cleanup.Lock()
+	defer cleanup.Unlock()
+	for path := range cleanup.temps {
+		os.Remove(path)
+	}
+	cleanup.temps = make(map[string]bool)
+	cleanup.outputs = make(map[string]bool)
+}
+
+// handleSignals 在收到 SIGINT/SIGTERM 时取消 ctx，清理后以 exitInterrupted 退出
+func handleSignals(cancel context.CancelFunc) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		cancel()
+		fatalf(exitInterrupted, "收到信号 %v，已终止并清理临时文件", sig)
+	}()
+}
@@ -0,0 +1,26 @@
+package main
+
+import "github.com/go-audio/audio"
+
+// 幅度不低于 clippingDBFS 的采样视为削波；削波采样占比超过
+// clippingWarnPercent 时给出警告
+const (
+	clippingDBFS        = -0.1
+	clippingWarnPercent = 0.1
+)
+
+// clippedPercent 返回 buf 中接近满幅的采样所占的百分比
+func clippedPercent(buf *audio.IntBuffer) float64 {
+	if len(buf.Data) == 0 {
+		return 0
+	}
+	bits := buf.SourceBitDepth
+	limit := int(float64(int(1)<<(bits-1)-1) * dbToLinear(clippingDBFS))
+	n := 0
+	for _, v := range buf.Data {
+		if sampleAmplitude(v, bits) >= limit {
+			n++
+		}
+	}
+	return float64(n) / float64(len(buf.Data)) * 100
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// clipRange 是从源文件中截取的区间（秒），out 为 0 表示截到结尾
+type clipRange struct {
+	in, out float64
+}
+
+var clipRangeRe = regexp.MustCompile(`^(\d*\.?\d*)-(\d*\.?\d*)$`)
+
+// splitClipRange 解析 `clip.wav@1.2-3.4` 形式的参数，返回文件模式和截取区间；
+// @ 之后不是合法区间时整个参数视为文件模式
+func splitClipRange(arg string) (string, *clipRange, error) {
+	i := len(arg) - 1
+	for i >= 0 && arg[i] != '@' {
+		i--
+	}
+	if i <= 0 {
+		return arg, nil, nil
+	}
+	m := clipRangeRe.FindStringSubmatch(arg[i+1:])
+	if m == nil || (m[1] == "" && m[2] == "") {
+		return arg, nil, nil
+	}
+	r := &clipRange{}
+	var err error
+	if m[1] != "" {
+		if r.in, err = strconv.ParseFloat(m[1], 64); err != nil {
+			return "", nil, fmt.Errorf("无效的截取区间 %s: %v", arg, err)
+		}
+	}
+	if m[2] != "" {
+		if r.out, err = strconv.ParseFloat(m[2], 64); err != nil {
+			return "", nil, fmt.Errorf("无效的截取区间 %s: %v", arg, err)
+		}
+	}
+	return arg[:i], r, nil
+}
+
+// frames 把区间换算为帧下标并检查边界，total 为源片段的总帧数
+func (r *clipRange) frames(rate, total int) (int, int, error) {
+	from := int(r.in*float64(rate) + 0.5)
+	to := total
+	if r.out > 0 {
+		to = int(r.out*float64(rate) + 0.5)
+	}
+	duration := float64(total) / float64(rate)
+	if r.in < 0 || from > total {
+		return 0, 0, fmt.Errorf("截取起点 %g 超出片段时长 %g", r.in, duration)
+	}
+	if to > total {
+		return 0, 0, fmt.Errorf("截取终点 %g 超出片段时长 %g", r.out, duration)
+	}
+	if from >= to {
+		return 0, 0, fmt.Errorf("截取区间 [%g, %g] 无效，起点必须小于终点", r.in, r.out)
+	}
+	return from, to, nil
+}
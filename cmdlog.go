@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"sync"
+)
+
+// commandRecord 是 -command-log 中的一条记录
+type commandRecord struct {
+	Argv     []string `json:"argv"`
+	ExitCode int      `json:"exitCode"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// commandLog 收集本次运行执行过的外部命令，path 为空时不记录
+var commandLog struct {
+	sync.Mutex
+	path    string
+	records []commandRecord
+}
+
+// recordCommand 记录一次命令执行及其退出状态
+func recordCommand(argv []string, err error) {
+	commandLog.Lock()
+	defer commandLog.Unlock()
+	if commandLog.path == "" {
+		return
+	}
+	rec := commandRecord{Argv: argv}
+	if err != nil {
+		rec.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			rec.ExitCode = exitErr.ExitCode()
+		}
+		rec.Error = err.Error()
+	}
+	commandLog.records = append(commandLog.records, rec)
+}
+
+// runCommand 启动 cmd 并等待其结束：运行期间登记进程以便中断时终止，
+// 结束后记录到 -command-log。ffmpeg、ffplay、ffprobe 都经由这里执行
+func runCommand(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		recordCommand(cmd.Args, err)
+		return err
+	}
+	trackProcess(cmd.Process)
+	err := cmd.Wait()
+	untrackProcess(cmd.Process)
+	recordCommand(cmd.Args, err)
+	return err
+}
+
+// flushCommandLog 把记录写成 JSON 数组
+func flushCommandLog() error {
+	commandLog.Lock()
+	defer commandLog.Unlock()
+	if commandLog.path == "" {
+		return nil
+	}
+	records := commandLog.records
+	if records == nil {
+		records = []commandRecord{}
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(commandLog.path, data, 0644)
+}
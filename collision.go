@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// 键名冲突时的处理方式
+const (
+	collisionError   = "error"
+	collisionSkip    = "skip"
+	collisionReplace = "replace"
+)
+
+// resolveInputCollisions 在解码前按 policy 处理生成相同键名的输入：
+// skip 保留先出现的，replace 保留后出现的（位置随后出现的那个），error 返回错误
+func resolveInputCollisions(inputs []clipSpec, policy string) ([]clipSpec, error) {
+	last := make(map[string]int)
+	for i, in := range inputs {
+		key := in.clipKey()
+		if j, ok := last[key]; ok && policy == collisionError {
+			return nil, fmt.Errorf("%s 与 %s 生成相同的键 %s（可用 -on-collision skip 或 replace）", inputs[j].path, in.path, key)
+		}
+		if _, ok := last[key]; !ok || policy == collisionReplace {
+			last[key] = i
+		}
+	}
+	out := inputs[:0:0]
+	for i, in := range inputs {
+		if last[in.clipKey()] == i {
+			out = append(out, in)
+		}
+	}
+	return out, nil
+}
+
+// resolveSpriteCollisions 处理 -reverse-suffix、-split-silence 等派生键名与已有键的冲突，
+// 规则同 resolveInputCollisions；被丢弃片段的音频仍留在输出中
+func resolveSpriteCollisions(sprites []sprite, policy string) ([]sprite, error) {
+	last := make(map[string]int)
+	for i, sp := range sprites {
+		if j, ok := last[sp.key]; ok && policy == collisionError {
+			return nil, fmt.Errorf("键 %s 重复（来自 %s 和 %s），可用 -on-collision skip 或 replace", sp.key, filepath.Base(sprites[j].source), filepath.Base(sp.source))
+		}
+		if _, ok := last[sp.key]; !ok || policy == collisionReplace {
+			last[sp.key] = i
+		}
+	}
+	out := sprites[:0:0]
+	for i, sp := range sprites {
+		if last[sp.key] == i {
+			out = append(out, sp)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-audio/audio"
+)
+
+// fmt 块中的格式标记；除 PCM 和浮点外的编码（IMA ADPCM、μ-law 等）交给 ffmpeg 解码
+const (
+	wavFormatPCM        = 1
+	wavFormatExtensible = 0xFFFE
+)
+
+// unsupportedCodecError 表示 WAV 使用了 go-audio 无法解码的编码
+type unsupportedCodecError struct {
+	tag uint16
+}
+
+func (e *unsupportedCodecError) Error() string {
+	return fmt.Sprintf("不支持的 WAV 编码 0x%04X", e.tag)
+}
+
+// ffmpegDecodeError 包装 ffmpeg 解码回退中的错误，便于按 ffmpeg 的退出码处理
+type ffmpegDecodeError struct {
+	err error
+}
+
+func (e *ffmpegDecodeError) Error() string { return "ffmpeg 解码失败: " + e.err.Error() }
+func (e *ffmpegDecodeError) Unwrap() error { return e.err }
+
+// truncatedDataError 表示 data 块声明的长度与实际解码出的采样数不一致，
+// 通常是文件在传输中被截断；返回该错误时缓冲中仍是已解码的部分
+type truncatedDataError struct {
+	path              string
+	declared, decoded int
+}
+
+func (e *truncatedDataError) Error() string {
+	return fmt.Sprintf("%s 的 data 块声明 %d 个采样，实际只有 %d 个，文件可能被截断", e.path, e.declared, e.decoded)
+}
+
+// wavFmt 是 fmt 块中本工具关心的字段
+type wavFmt struct {
+	// tag 是格式标记，WAVE_FORMAT_EXTENSIBLE 时取子格式
+	tag uint16
+	// channelMask 是 WAVE_FORMAT_EXTENSIBLE 的声道掩码，其他格式为 0
+	channelMask uint32
+}
+
+// readWAVFmt 读取 fmt 块。不是 RIFF/WAVE 或找不到 fmt 块时返回零值，
+// 交由解码器报错；读取后 r 回到开头
+func readWAVFmt(r io.ReadSeeker) (wavFmt, error) {
+	defer r.Seek(0, io.SeekStart)
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return wavFmt{}, nil
+	}
+	if string(hdr[0:4]) != "RIFF" || string(hdr[8:12]) != "WAVE" {
+		return wavFmt{}, nil
+	}
+	for {
+		var ch [8]byte
+		if _, err := io.ReadFull(r, ch[:]); err != nil {
+			return wavFmt{}, nil
+		}
+		size := int64(binary.LittleEndian.Uint32(ch[4:8]))
+		if string(ch[0:4]) != "fmt " {
+			// 块按偶数字节对齐
+			if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+				return wavFmt{}, err
+			}
+			continue
+		}
+		if size < 2 {
+			return wavFmt{}, nil
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return wavFmt{}, err
+		}
+		f := wavFmt{tag: binary.LittleEndian.Uint16(body[0:2])}
+		// 扩展部分：cbSize(2) validBits(2) channelMask(4) subFormat GUID(16)
+		if f.tag == wavFormatExtensible && size >= 26 {
+			f.channelMask = binary.LittleEndian.Uint32(body[20:24])
+			f.tag = binary.LittleEndian.Uint16(body[24:26])
+		}
+		return f, nil
+	}
+}
+
+// clipChannelMask 读取输入片段的声道掩码，没有时返回 0
+func clipChannelMask(in clipSpec) (uint32, error) {
+	var r io.ReadSeeker
+	if in.archive != "" {
+		data, err := readArchiveEntry(in.archive, in.entry)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(data)
+	} else {
+		f, err := os.Open(in.path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		r = f
+	}
+	fmtChunk, err := readWAVFmt(r)
+	return fmtChunk.channelMask, err
+}
+
+// ffmpegDecode 用 ffmpeg 把 input 转成 16 位 PCM 临时文件后再解码
+func ffmpegDecode(ctx context.Context, input string) (*audio.IntBuffer, error) {
+	tmp, err := ioutil.TempFile("", "audiosprite-*.wav")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	addTemp(tmp.Name())
+	defer removeTemp(tmp.Name())
+	out, err := runFFmpeg(ctx, "-y", "-i", input, "-codec:a", "pcm_s16le", tmp.Name())
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, &ffmpegDecodeError{ctx.Err()}
+		}
+		return nil, &ffmpegDecodeError{fmt.Errorf("%w, %s", err, string(out))}
+	}
+	return decodeWAV(tmp.Name())
+}
+
+// ffmpegDecodeClip 对 go-audio 无法解码的片段回退到 ffmpeg，压缩包条目先解压到临时文件
+func ffmpegDecodeClip(ctx context.Context, in clipSpec) (*audio.IntBuffer, error) {
+	if in.archive == "" {
+		return ffmpegDecode(ctx, in.path)
+	}
+	data, err := readArchiveEntry(in.archive, in.entry)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempFile("", "audiosprite-*.wav")
+	if err != nil {
+		return nil, err
+	}
+	addTemp(tmp.Name())
+	defer removeTemp(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ffmpegDecode(ctx, tmp.Name())
+}
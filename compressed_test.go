@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// imaADPCM 是 IMA ADPCM 的 fmt 格式标记
+const imaADPCM = 0x11
+
+func TestDecodeADPCMReturnsUnsupportedCodec(t *testing.T) {
+	wav := wavFixture{
+		tag:      imaADPCM,
+		channels: 1,
+		rate:     22050,
+		bits:     4,
+		// cbSize=2，samplesPerBlock=505
+		fmtExtra: []byte{2, 0, 0xF9, 0x01},
+		data:     make([]byte, 256),
+	}
+	r := bytes.NewReader(wav.bytes())
+	f, err := readWAVFmt(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.tag != imaADPCM {
+		t.Errorf("tag = 0x%X, want 0x%X", f.tag, imaADPCM)
+	}
+	if pos, _ := r.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Errorf("readWAVFmt 之后位于 %d，应回到开头", pos)
+	}
+
+	_, err = decodeWAVReader(bytes.NewReader(wav.bytes()), "adpcm.wav")
+	var codecErr *unsupportedCodecError
+	if !errors.As(err, &codecErr) {
+		t.Fatalf("err = %v, want *unsupportedCodecError", err)
+	}
+	if codecErr.tag != imaADPCM {
+		t.Errorf("codecErr.tag = 0x%X, want 0x%X", codecErr.tag, imaADPCM)
+	}
+}
+
+func TestReadWAVFmtNotRIFF(t *testing.T) {
+	f, err := readWAVFmt(bytes.NewReader([]byte("ID3\x04 not a wav file")))
+	if err != nil || f != (wavFmt{}) {
+		t.Errorf("readWAVFmt = %+v, %v; want 零值交由解码器报错", f, err)
+	}
+}
+
+// extensibleFmt 返回 WAVE_FORMAT_EXTENSIBLE 的 fmt 扩展部分：
+// cbSize、validBits、声道掩码和以 tag 开头的子格式 GUID
+func extensibleFmt(validBits uint16, mask uint32, tag uint16) []byte {
+	var b bytes.Buffer
+	for _, v := range []interface{}{uint16(22), validBits, mask, tag} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	// KSDATAFORMAT_SUBTYPE 的其余 14 字节
+	b.Write([]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71})
+	return b.Bytes()
+}
+
+func TestExtensibleHeader(t *testing.T) {
+	// 5.1(side)：FL FR FC LFE SL SR
+	const mask = 0x60F
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []int16{1000, 2000, 1000, 5000, 2000, 0})
+	wav := wavFixture{
+		tag:      wavFormatExtensible,
+		channels: 6,
+		rate:     48000,
+		bits:     16,
+		fmtExtra: extensibleFmt(16, mask, wavFormatPCM),
+		data:     data.Bytes(),
+	}
+
+	f, err := readWAVFmt(bytes.NewReader(wav.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.tag != wavFormatPCM || f.channelMask != mask {
+		t.Fatalf("readWAVFmt = {tag: 0x%X, channelMask: 0x%X}, want {tag: 0x1, channelMask: 0x%X}", f.tag, f.channelMask, mask)
+	}
+
+	coeffs, err := downmixCoefficients(6, f.channelMask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SL/SR 分别按 -3dB 进入左右声道
+	want := [][2]float64{{1, 0}, {0, 1}, {ituCenter, ituCenter}, {0, 0}, {ituCenter, 0}, {0, ituCenter}}
+	if !reflect.DeepEqual(coeffs, want) {
+		t.Errorf("downmixCoefficients = %v, want %v", coeffs, want)
+	}
+
+	buf, err := decodeWAVReader(bytes.NewReader(wav.bytes()), "surround.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := surroundDownmix(buf, 2, f.channelMask); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3121, 2707}; !reflect.DeepEqual(buf.Data, want) {
+		t.Errorf("下混结果 = %v, want %v", buf.Data, want)
+	}
+}
+
+func TestDownmixCoefficientsMaskMismatch(t *testing.T) {
+	// 掩码只有 FL FR 两位，却有 6 个声道
+	if _, err := downmixCoefficients(6, 0x3); err == nil {
+		t.Error("声道掩码与声道数不符时应返回错误")
+	}
+}
+
+func TestDecodeTruncatedWAV(t *testing.T) {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []int16{100, -100, 200})
+	// data 块声明 8 个采样，文件中只有 3 个
+	wav := wavFixture{tag: wavFormatPCM, channels: 1, rate: 8000, bits: 16, data: data.Bytes(), dataSize: 16}
+	buf, err := decodeWAVReader(bytes.NewReader(wav.bytes()), "trunc.wav")
+	var truncErr *truncatedDataError
+	if !errors.As(err, &truncErr) {
+		t.Fatalf("err = %v, want *truncatedDataError", err)
+	}
+	if truncErr.declared != 8 || truncErr.decoded != 3 {
+		t.Errorf("declared/decoded = %d/%d, want 8/3", truncErr.declared, truncErr.decoded)
+	}
+	// -allow-truncated 时使用已解码的部分
+	if buf == nil || !reflect.DeepEqual(buf.Data, []int{100, -100, 200}) {
+		t.Errorf("已解码的部分 = %v", buf)
+	}
+
+	// 长度相符的文件不报错
+	wav.dataSize = 0
+	if _, err := decodeWAVReader(bytes.NewReader(wav.bytes()), "ok.wav"); err != nil {
+		t.Errorf("完整的文件返回错误: %v", err)
+	}
+}
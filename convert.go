@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// outputFormats 是 -format 支持的输出格式
+var outputFormats = map[string]bool{"wav": true, "mp3": true, "ogg": true}
+
+// parseFormatList 解析逗号分隔的 -format，如 mp3,ogg；
+// 统一转为小写并去掉重复项，第一个格式为主格式
+func parseFormatList(s string) ([]string, error) {
+	var formats []string
+	seen := make(map[string]bool)
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !outputFormats[f] {
+			return nil, fmt.Errorf("不支持的格式: %s，仅支持 wav, mp3, ogg", f)
+		}
+		if !seen[f] {
+			seen[f] = true
+			formats = append(formats, f)
+		}
+	}
+	return formats, nil
+}
+
+// hasFormat 判断 formats 中是否包含 f
+func hasFormat(formats []string, f string) bool {
+	for _, x := range formats {
+		if x == f {
+			return true
+		}
+	}
+	return false
+}
+
+// conversion 是一次待执行的 ffmpeg 转换
+type conversion struct {
+	input, output, format string
+}
+
+// runConversions 以最多 jobs 个并发执行全部转换。各转换只读取已写好的中间 WAV，
+// 彼此互不影响：一个失败时其余照常完成，结束后把所有错误合并返回
+func runConversions(ctx context.Context, convs []conversion, jobs int, optsFor func(string) convertOptions) error {
+	errs := make([]error, len(convs))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, c := range convs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := ffmpegConvert(ctx, c.input, c.output, c.format, optsFor(c.format)); err != nil {
+				errs[i] = fmt.Errorf("转换 %s 失败: %w", c.output, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
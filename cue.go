@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// cueFramesPerSecond 是 CUE 规范中每秒的帧数（CD 扇区）
+const cueFramesPerSecond = 75
+
+// cueTimestamp 把秒数格式化为 CUE 的 MM:SS:FF，FF 为 1/75 秒，四舍五入到最近的帧
+func cueTimestamp(seconds float64) string {
+	total := int(math.Round(seconds * cueFramesPerSecond))
+	ff := total % cueFramesPerSecond
+	ss := total / cueFramesPerSecond % 60
+	mm := total / cueFramesPerSecond / 60
+	return fmt.Sprintf("%02d:%02d:%02d", mm, ss, ff)
+}
+
+// cueFileType 返回 CUE FILE 行的文件类型
+func cueFileType(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".mp3") {
+		return "MP3"
+	}
+	return "WAVE"
+}
+
+// writeCueSheet 为每个片段写一个 TRACK，INDEX 01 位于片段起点，
+// 片段所在的音频文件以相对 CUE 文件的路径引用
+func writeCueSheet(path string, sprites []sprite, parts []atlasPart, resources []string, rate int) error {
+	if len(sprites) > 99 {
+		log.Printf("警告: CUE 规范最多 99 条音轨，%s 包含 %d 个片段，部分播放器可能无法识别", path, len(sprites))
+	}
+	var b strings.Builder
+	file := -1
+	for i, sp := range sprites {
+		if sp.resource != file {
+			file = sp.resource
+			fmt.Fprintf(&b, "FILE \"%s\" %s\n", cueRelPath(path, resources[file]), cueFileType(resources[file]))
+		}
+		start := float64(sp.start-parts[sp.resource].start) / float64(rate)
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n", i+1)
+		fmt.Fprintf(&b, "    TITLE \"%s\"\n", strings.ReplaceAll(sp.key, `"`, `'`))
+		fmt.Fprintf(&b, "    INDEX 01 %s\n", cueTimestamp(start))
+	}
+	return ioutil.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// cueRelPath 返回 target 相对 cuePath 所在目录的路径，无法计算时原样返回
+func cueRelPath(cuePath, target string) string {
+	absCue, err1 := filepath.Abs(filepath.Dir(cuePath))
+	absTarget, err2 := filepath.Abs(target)
+	if err1 != nil || err2 != nil {
+		return target
+	}
+	rel, err := filepath.Rel(absCue, absTarget)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-audio/audio"
+)
+
+// removeDCOffset 计算每个声道的采样均值并从该声道减去，返回各声道去掉的偏移量
+// （相对满幅的比例）。8 位无符号采样的零点是 128，超出位深范围的值被钳制
+func removeDCOffset(buf *audio.IntBuffer) []float64 {
+	ch := buf.Format.NumChannels
+	frames := len(buf.Data) / ch
+	if frames == 0 {
+		return nil
+	}
+	bits := buf.SourceBitDepth
+	offset := 0
+	if bits == 8 {
+		offset = 128
+	}
+	maxVal := int(1)<<(bits-1) - 1
+	minVal := -(int(1) << (bits - 1))
+
+	removed := make([]float64, ch)
+	for c := 0; c < ch; c++ {
+		sum := 0.0
+		for i := c; i < frames*ch; i += ch {
+			sum += float64(buf.Data[i] - offset)
+		}
+		mean := int(math.Round(sum / float64(frames)))
+		if mean == 0 {
+			continue
+		}
+		for i := c; i < frames*ch; i += ch {
+			v := buf.Data[i] - offset - mean
+			if v > maxVal {
+				v = maxVal
+			} else if v < minVal {
+				v = minVal
+			}
+			buf.Data[i] = v + offset
+		}
+		removed[c] = float64(mean) / float64(int(1)<<(bits-1))
+	}
+	return removed
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveDCOffset(t *testing.T) {
+	// 左声道是以 +1000 为中心的方波，右声道没有偏移
+	buf := stereoBuffer(16,
+		2000, 500,
+		0, -500,
+		2000, 500,
+		0, -500,
+	)
+	removed := removeDCOffset(buf)
+	if want := []int{1000, 500, -1000, -500, 1000, 500, -1000, -500}; !reflect.DeepEqual(buf.Data, want) {
+		t.Errorf("去偏移后 = %v, want %v", buf.Data, want)
+	}
+	if want := []float64{1000.0 / 32768, 0}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+}
+
+func TestRemoveDCOffsetClamps(t *testing.T) {
+	// 均值为 -16384，最大值减去均值后超出 16 位范围
+	buf := monoBuffer(16, 32767, -32768, -32768, -32768)
+	removeDCOffset(buf)
+	if want := []int{32767, -16384, -16384, -16384}; !reflect.DeepEqual(buf.Data, want) {
+		t.Errorf("去偏移后 = %v, want %v", buf.Data, want)
+	}
+}
+
+func TestRemoveDCOffset8Bit(t *testing.T) {
+	// 8 位无符号采样以 128 为零点，偏移 +10
+	buf := monoBuffer(8, 148, 128, 148, 128)
+	removed := removeDCOffset(buf)
+	if want := []int{138, 118, 138, 118}; !reflect.DeepEqual(buf.Data, want) {
+		t.Errorf("去偏移后 = %v, want %v", buf.Data, want)
+	}
+	if want := []float64{10.0 / 128}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// hashSamples 返回输出格式与采样数据的 SHA-256，用于识别内容完全相同的片段；
+// 计入格式是为了只在同一个音频文件内复用区间
+func hashSamples(format string, data []int) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(format))
+	var b [4]byte
+	for _, v := range data {
+		binary.LittleEndian.PutUint32(b[:], uint32(int32(v)))
+		h.Write(b[:])
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
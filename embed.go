@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// embedWarnSize 是 -embed 后内嵌音频总大小的提示阈值（字节），超过时给出警告
+const embedWarnSize = 1 << 20
+
+// audioMIME 是各输出格式的 data URI MIME 类型
+var audioMIME = map[string]string{
+	"wav": "audio/wav",
+	"mp3": "audio/mpeg",
+	"ogg": "audio/ogg",
+}
+
+// dataURIs 读取 resources 中的音频，返回对应的 base64 data URI 列表及其总长度
+func dataURIs(resources []string) ([]string, int, error) {
+	uris := make([]string, len(resources))
+	total := 0
+	for i, r := range resources {
+		data, err := ioutil.ReadFile(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		mime := audioMIME[strings.TrimPrefix(filepath.Ext(r), ".")]
+		uris[i] = "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
+		total += len(uris[i])
+	}
+	return uris, total, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix 是环境变量默认值的前缀，如 -format 对应 AUDIOSPRITE_FORMAT
+const envPrefix = "AUDIOSPRITE_"
+
+// envName 返回选项对应的环境变量名，- 换成 _ 并转为大写
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnvDefaults 在解析命令行前用环境变量设置选项，命令行中再次指定时覆盖
+func applyEnvDefaults(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if e := fs.Set(f.Name, v); e != nil {
+			err = fmt.Errorf("环境变量 %s=%q 无效: %v", envName(f.Name), v, e)
+		}
+	})
+	return err
+}
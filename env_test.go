@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestApplyEnvDefaults(t *testing.T) {
+	newFlags := func() (*flag.FlagSet, *string, *float64) {
+		fs := flag.NewFlagSet("go-audiosprite", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		format := fs.String("format", "wav", "")
+		minGap := fs.Float64("min-gap", 0, "")
+		return fs, format, minGap
+	}
+
+	t.Setenv("AUDIOSPRITE_FORMAT", "ogg")
+	t.Setenv("AUDIOSPRITE_MIN_GAP", "0.05")
+
+	// 环境变量成为默认值
+	fs, format, minGap := newFlags()
+	if err := applyEnvDefaults(fs); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if *format != "ogg" || *minGap != 0.05 {
+		t.Errorf("format = %q, min-gap = %g, want ogg, 0.05", *format, *minGap)
+	}
+
+	// 命令行中指定的值覆盖环境变量
+	fs, format, minGap = newFlags()
+	if err := applyEnvDefaults(fs); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-format", "mp3"}); err != nil {
+		t.Fatal(err)
+	}
+	if *format != "mp3" || *minGap != 0.05 {
+		t.Errorf("format = %q, min-gap = %g, want mp3, 0.05", *format, *minGap)
+	}
+
+	// 无法解析的值报错，而不是被忽略
+	t.Setenv("AUDIOSPRITE_MIN_GAP", "fast")
+	fs, _, _ = newFlags()
+	if err := applyEnvDefaults(fs); err == nil {
+		t.Error("AUDIOSPRITE_MIN_GAP=fast 应返回错误")
+	}
+}
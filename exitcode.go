@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// 退出码，按失败类型区分，方便 CI 根据退出码分支处理
+const (
+	exitInput    = 2 // 参数错误或输入文件无效
+	exitFFmpeg   = 3 // 找不到 ffmpeg 或 ffmpeg 执行失败
+	exitIO       = 4 // 输出文件读写失败
+	exitMismatch = 5 // verify 发现清单与音频不一致
+
+	exitInterrupted = 130 // 收到中断信号（128 + SIGINT）
+)
+
+// fatalf 打印错误信息、清理临时与未完成的文件后以指定退出码退出，
+// 取代 log.Fatalf 的统一出口
+func fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	if err := flushCommandLog(); err != nil {
+		log.Printf("写入命令日志失败: %v", err)
+	}
+	abortCleanup()
+	os.Exit(code)
+}
+
+// ffmpegExitCode 返回 ffmpeg 相关错误的退出码；
+// 找不到 ffmpeg 可执行文件时额外给出提示
+func ffmpegExitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		log.Printf("未找到 ffmpeg，请确认已安装并位于 PATH 中")
+	}
+	return exitFFmpeg
+}
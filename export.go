@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// addMilliseconds 为每个条目补充整数毫秒的 startMs/endMs，取 round(秒*1000)
+func addMilliseconds(spritemap map[string]SpriteMapEntry) {
+	for key, entry := range spritemap {
+		startMs := toMs(entry.Start)
+		endMs := toMs(entry.End)
+		entry.StartMs = &startMs
+		entry.EndMs = &endMs
+		spritemap[key] = entry
+	}
+}
+
+// addTimeUnits 为每个条目补充以 1/base 秒为单位的 startUnits/endUnits
+func addTimeUnits(spritemap map[string]SpriteMapEntry, base int) {
+	for key, entry := range spritemap {
+		start := toUnits(entry.Start, base)
+		end := toUnits(entry.End, base)
+		entry.StartUnits = &start
+		entry.EndUnits = &end
+		spritemap[key] = entry
+	}
+}
+
+// looping 报告条目是否循环，loop 被 -no-loop-in-export 去掉时为 false
+func (e SpriteMapEntry) looping() bool {
+	return e.Loop != nil && *e.Loop
+}
+
+// stripLoops 去掉所有条目的循环信息，供不认识 loop 字段的引擎使用
+func stripLoops(spritemap map[string]SpriteMapEntry) {
+	for key, entry := range spritemap {
+		entry.Loop = nil
+		entry.LoopStart = nil
+		entry.LoopEnd = nil
+		spritemap[key] = entry
+	}
+}
+
+// addSources 为每个条目补充源文件路径
+func addSources(spritemap map[string]SpriteMapEntry, sprites []sprite) {
+	for _, sp := range sprites {
+		entry, ok := spritemap[sp.key]
+		if !ok || sp.source == "" {
+			continue
+		}
+		entry.Source = sp.source
+		spritemap[sp.key] = entry
+	}
+}
+
+// addMeta 把 -merge-sidecar 读到的字段原样放进条目的 meta
+func addMeta(spritemap map[string]SpriteMapEntry, sprites []sprite) {
+	for _, sp := range sprites {
+		entry, ok := spritemap[sp.key]
+		if !ok || sp.meta == nil {
+			continue
+		}
+		entry.Meta = sp.meta
+		spritemap[sp.key] = entry
+	}
+}
+
+// checkFinite 确认每个条目的时间都是有限值，json.Marshal 遇到 NaN/Inf 会失败，
+// 在写出任何清单前给出指向源文件的错误
+func checkFinite(spritemap map[string]SpriteMapEntry, sprites []sprite) error {
+	for _, sp := range sprites {
+		entry := spritemap[sp.key]
+		values := []float64{entry.Start, entry.End}
+		if entry.LoopStart != nil {
+			values = append(values, *entry.LoopStart, *entry.LoopEnd)
+		}
+		for _, v := range values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("片段 %s（%s）的时间无效: start=%v end=%v", sp.key, sp.source, entry.Start, entry.End)
+			}
+		}
+	}
+	return nil
+}
+
+// addDurationPct 为每个条目补充其时长占整个输出总时长（含间隔）的百分比
+func addDurationPct(spritemap map[string]SpriteMapEntry, total float64) {
+	if total <= 0 {
+		return
+	}
+	for key, entry := range spritemap {
+		pct := (entry.End - entry.Start) / total * 100
+		entry.DurationPct = &pct
+		spritemap[key] = entry
+	}
+}
+
+// extraExports 是 -export 中可额外写出的清单格式，各自写到 基名.<格式>.json
+var extraExports = map[string]func(spritemap map[string]SpriteMapEntry, resources []string) interface{}{
+	"howler":   howlerManifest,
+	"createjs": createjsManifest,
+	"jukebox":  jukeboxManifest,
+}
+
+// singleResourceExports 是只描述同一音频的不同格式、不支持拆分输出的额外格式
+var singleResourceExports = map[string]bool{"howler": true, "jukebox": true}
+
+// parseExportList 解析 -export 的逗号列表，返回主 JSON 的时间字段模式和额外格式
+func parseExportList(s string) (mode string, extras []string, err error) {
+	mode = "seconds"
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch {
+		case name == "seconds" || name == "both":
+			mode = name
+		case extraExports[name] != nil:
+			extras = append(extras, name)
+		default:
+			return "", nil, fmt.Errorf("不支持的 -export 取值: %s，仅支持 seconds, both, howler, createjs, jukebox", name)
+		}
+	}
+	if seen["seconds"] && seen["both"] {
+		return "", nil, fmt.Errorf("-export 中 seconds 与 both 不能同时指定")
+	}
+	return mode, extras, nil
+}
+
+// sortedKeys 按起点、键名排序，使导出结果稳定
+func sortedKeys(spritemap map[string]SpriteMapEntry) []string {
+	keys := make([]string, 0, len(spritemap))
+	for k := range spritemap {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := spritemap[keys[i]], spritemap[keys[j]]
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func toMs(sec float64) int64 {
+	return toUnits(sec, 1000)
+}
+
+// toUnits 把秒数换算为 1/base 秒的整数单位，四舍五入
+func toUnits(sec float64, base int) int64 {
+	return int64(math.Round(sec * float64(base)))
+}
+
+// howlerManifest 生成 Howler.js 的构造参数 {src, sprite}，
+// sprite 的值为 [起点毫秒, 时长毫秒(, true 表示循环)]。
+// Howler 的 src 是同一音频的不同格式，因此只适用于未拆分的输出
+func howlerManifest(spritemap map[string]SpriteMapEntry, resources []string) interface{} {
+	sprite := make(map[string][]interface{}, len(spritemap))
+	for key, entry := range spritemap {
+		v := []interface{}{toMs(entry.Start), toMs(entry.End) - toMs(entry.Start)}
+		if entry.looping() {
+			v = append(v, true)
+		}
+		sprite[key] = v
+	}
+	return map[string]interface{}{
+		"src":    resources,
+		"sprite": sprite,
+	}
+}
+
+type createjsSprite struct {
+	ID        string `json:"id"`
+	StartTime int64  `json:"startTime"`
+	Duration  int64  `json:"duration"`
+}
+
+type createjsSource struct {
+	Src  string `json:"src"`
+	Data struct {
+		AudioSprite []createjsSprite `json:"audioSprite"`
+	} `json:"data"`
+}
+
+// createjsManifest 生成 SoundJS 的 registerSounds 清单，每个含片段的音频文件一项，时间为毫秒
+func createjsManifest(spritemap map[string]SpriteMapEntry, resources []string) interface{} {
+	sources := make([]*createjsSource, len(resources))
+	var out []*createjsSource
+	for _, key := range sortedKeys(spritemap) {
+		entry := spritemap[key]
+		src := sources[entry.Resource]
+		if src == nil {
+			src = &createjsSource{Src: resources[entry.Resource]}
+			sources[entry.Resource] = src
+			out = append(out, src)
+		}
+		src.Data.AudioSprite = append(src.Data.AudioSprite, createjsSprite{
+			ID:        key,
+			StartTime: toMs(entry.Start),
+			Duration:  toMs(entry.End) - toMs(entry.Start),
+		})
+	}
+	return out
+}
+
+type jukeboxEntry struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Loop  bool    `json:"loop"`
+}
+
+// jukeboxOutput 的字段顺序与上游 audiosprite 的 jukebox 输出一致
+type jukeboxOutput struct {
+	Resources []string                `json:"resources"`
+	Spritemap map[string]jukeboxEntry `json:"spritemap"`
+	// Autoplay 是自动播放的键名，本工具不设置，固定为 null
+	Autoplay *string `json:"autoplay"`
+}
+
+// jukeboxManifest 生成 Jukebox 引擎使用的清单，时间为秒；
+// resources 与 Howler 一样视为同一音频的不同格式
+func jukeboxManifest(spritemap map[string]SpriteMapEntry, resources []string) interface{} {
+	out := jukeboxOutput{Resources: resources, Spritemap: make(map[string]jukeboxEntry, len(spritemap))}
+	for key, entry := range spritemap {
+		out.Spritemap[key] = jukeboxEntry{Start: entry.Start, End: entry.End, Loop: entry.looping()}
+	}
+	return out
+}
+
+// stringTimeDecimals 是 -json-numbers-as-strings 输出 start/end 时的小数位数
+const stringTimeDecimals = 6
+
+// quotedSeconds 把秒数序列化为固定小数位的字符串，如 "0.340000"
+type quotedSeconds float64
+
+func (q quotedSeconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(float64(q), 'f', stringTimeDecimals, 64))
+}
+
+// stringTimesEntry 覆盖 SpriteMapEntry 的 start/end，其余字段照常输出
+type stringTimesEntry struct {
+	// Start/End 写在嵌入字段之前，保持 start、end 在最前的字段顺序
+	Start quotedSeconds `json:"start"`
+	End   quotedSeconds `json:"end"`
+	SpriteMapEntry
+}
+
+// stringTimesJSON 是 -json-numbers-as-strings 时的主 JSON，只替换 spritemap
+type stringTimesJSON struct {
+	SpriteJSON
+	Spritemap map[string]stringTimesEntry `json:"spritemap"`
+}
+
+// withStringTimes 返回 start/end 以字符串输出的 s，供把 JSON 数字解析为 double 会丢精度的引擎使用
+func withStringTimes(s SpriteJSON) stringTimesJSON {
+	out := stringTimesJSON{SpriteJSON: s, Spritemap: make(map[string]stringTimesEntry, len(s.Spritemap))}
+	for key, entry := range s.Spritemap {
+		out.Spritemap[key] = stringTimesEntry{Start: quotedSeconds(entry.Start), End: quotedSeconds(entry.End), SpriteMapEntry: entry}
+	}
+	return out
+}
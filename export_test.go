@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAddMilliseconds(t *testing.T) {
+	spritemap := map[string]SpriteMapEntry{
+		"a": {Start: 0, End: 0.1234},
+		"b": {Start: 0.1235, End: 1.0005},
+		// 帧数换算得到的秒数，如 44100 Hz 下的第 1 帧与第 22051 帧
+		"c": {Start: 1.0 / 44100, End: 22051.0 / 44100},
+		"d": {Start: 2.9994999, End: 3.0005001},
+	}
+	addMilliseconds(spritemap)
+	for key, entry := range spritemap {
+		if entry.StartMs == nil || entry.EndMs == nil {
+			t.Fatalf("%s 缺少 startMs/endMs", key)
+		}
+		if want := int64(math.Round(entry.Start * 1000)); *entry.StartMs != want {
+			t.Errorf("%s startMs = %d, want %d", key, *entry.StartMs, want)
+		}
+		if want := int64(math.Round(entry.End * 1000)); *entry.EndMs != want {
+			t.Errorf("%s endMs = %d, want %d", key, *entry.EndMs, want)
+		}
+	}
+	if got := *spritemap["b"].StartMs; got != 124 {
+		t.Errorf("0.1235s = %dms, want 124", got)
+	}
+	if got := *spritemap["c"].EndMs; got != 500 {
+		t.Errorf("22051/44100s = %dms, want 500", got)
+	}
+}
+
+// upstreamJukebox 是上游 audiosprite 以 `-e jukebox` 输出的清单（JSON.stringify 两空格缩进）
+const upstreamJukebox = `{
+  "resources": [
+    "sprite.ogg",
+    "sprite.mp3"
+  ],
+  "spritemap": {
+    "bang": {
+      "start": 0,
+      "end": 1.2,
+      "loop": false
+    },
+    "boom": {
+      "start": 3,
+      "end": 5.5,
+      "loop": true
+    }
+  },
+  "autoplay": null
+}`
+
+func TestJukeboxManifest(t *testing.T) {
+	loop := true
+	spritemap := map[string]SpriteMapEntry{
+		// 导出时忽略 jukebox 不认识的字段
+		"bang": {Start: 0, End: 1.2, Source: "bang.wav"},
+		"boom": {Start: 3, End: 5.5, Loop: &loop},
+	}
+	data, err := marshalManifest(jukeboxManifest(spritemap, []string{"sprite.ogg", "sprite.mp3"}), jsonStylePretty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != upstreamJukebox {
+		t.Errorf("jukebox:\n%s\nwant\n%s", data, upstreamJukebox)
+	}
+}
+
+func TestToUnits(t *testing.T) {
+	tests := []struct {
+		sec  float64
+		base int
+		want int64
+	}{
+		{0.5, 1000, 500},
+		{0.5, 44100, 22050},
+		{22051.0 / 44100, 44100, 22051},
+		{1.0 / 48000, 48000, 1},
+		{0.0166, 60, 1},
+		{0.025, 60, 2},
+		{1.5, 30, 45},
+		// 四舍五入：0.00125s × 400 = 0.5
+		{0.00125, 400, 1},
+		{0, 90000, 0},
+	}
+	for _, tt := range tests {
+		if got := toUnits(tt.sec, tt.base); got != tt.want {
+			t.Errorf("toUnits(%v, %d) = %d, want %d", tt.sec, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestAddTimeUnits(t *testing.T) {
+	spritemap := map[string]SpriteMapEntry{"a": {Start: 0.5, End: 1.25}}
+	addTimeUnits(spritemap, 60)
+	entry := spritemap["a"]
+	if entry.StartUnits == nil || *entry.StartUnits != 30 || entry.EndUnits == nil || *entry.EndUnits != 75 {
+		t.Errorf("startUnits/endUnits = %v/%v, want 30/75", entry.StartUnits, entry.EndUnits)
+	}
+}
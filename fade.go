@@ -0,0 +1,31 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-audio/audio"
+)
+
+// applyFade 对 buf 开头 inFrames 帧做线性淡入、末尾 outFrames 帧做线性淡出，
+// 淡入从 0 开始、淡出在最后一帧回到 0
+func applyFade(buf *audio.IntBuffer, inFrames, outFrames int) {
+	ch := buf.Format.NumChannels
+	frames := len(buf.Data) / ch
+	offset := 0
+	if buf.SourceBitDepth == 8 {
+		offset = 128
+	}
+	scale := func(frame int, factor float64) {
+		for i := frame * ch; i < (frame+1)*ch; i++ {
+			buf.Data[i] = int(math.Round(float64(buf.Data[i]-offset)*factor)) + offset
+		}
+	}
+	for f := 0; f < inFrames && f < frames; f++ {
+		scale(f, float64(f)/float64(inFrames))
+	}
+	for f := frames - outFrames; f < frames; f++ {
+		if f >= 0 {
+			scale(f, float64(frames-1-f)/float64(outFrames))
+		}
+	}
+}
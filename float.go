@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-audio/audio"
+)
+
+// wavFormatFloat 是 IEEE 浮点 WAV 的 fmt 格式标记
+const wavFormatFloat = 3
+
+// floatTargetBits 是浮点输入换算成整数采样时使用的位深，
+// 之后再由 convertBitDepth 统一到输出位深
+const floatTargetBits = 24
+
+// floatToInt 把 go-audio 按 int32 位模式读出的 32 位浮点采样
+// 还原为浮点值，并将 [-1, 1] 缩放到 bits 位整数范围（超出部分钳制）
+func floatToInt(buf *audio.IntBuffer, bits int) error {
+	if buf.SourceBitDepth != 32 {
+		return fmt.Errorf("不支持 %d 位浮点 WAV，仅支持 32 位", buf.SourceBitDepth)
+	}
+	scale := float64(int(1)<<(bits-1) - 1)
+	for i, v := range buf.Data {
+		f := float64(math.Float32frombits(uint32(int32(v))))
+		switch {
+		case math.IsNaN(f):
+			f = 0
+		case f > 1:
+			f = 1
+		case f < -1:
+			f = -1
+		}
+		buf.Data[i] = int(math.Round(f * scale))
+	}
+	buf.SourceBitDepth = bits
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeFloatWAV(t *testing.T) {
+	wav := wavFixture{
+		tag:      wavFormatFloat,
+		channels: 1,
+		rate:     44100,
+		bits:     32,
+		data:     float32LE(0.5, -0.5, 0, 1, -1, 2),
+	}
+	buf, err := decodeWAVReader(bytes.NewReader(wav.bytes()), "float.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.SourceBitDepth != floatTargetBits {
+		t.Errorf("SourceBitDepth = %d, want %d", buf.SourceBitDepth, floatTargetBits)
+	}
+	// ±0.5 对应 ±(2^23−1)/2，四舍五入为 ±4194304；超出 [-1, 1] 的值被钳制
+	full := 1<<23 - 1
+	want := []int{4194304, -4194304, 0, full, -full, full}
+	if !reflect.DeepEqual(buf.Data, want) {
+		t.Errorf("decoded = %v, want %v", buf.Data, want)
+	}
+}
+
+func TestFloatToIntRejectsNon32Bit(t *testing.T) {
+	buf := monoBuffer(64, 0)
+	if err := floatToInt(buf, 24); err == nil {
+		t.Error("64 位浮点输入应返回错误")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// formatEncoders 是各输出格式在 ffmpeg 中使用的音频编码器，wav 在内存中编码，不需要 ffmpeg
+var formatEncoders = map[string]string{
+	"mp3": "libmp3lame",
+	"ogg": "libvorbis",
+}
+
+// parseEncoders 解析 `ffmpeg -encoders` 的输出，返回其中音频编码器的名称集合。
+// 列表位于 ------ 分隔行之后，每行为 `标志 名称 描述`，标志首字母 A 表示音频
+func parseEncoders(out []byte) map[string]bool {
+	encoders := make(map[string]bool)
+	started := false
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if !started {
+			started = len(fields) == 1 && strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) >= 2 && strings.HasPrefix(fields[0], "A") {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
+
+// listFormats 实现 -list-formats：检查 ffmpeg 的编码器，逐个输出本机可用的输出格式
+func listFormats(ctx context.Context, w io.Writer) {
+	out, err := runFFmpeg(ctx, "-hide_banner", "-encoders")
+	var encoders map[string]bool
+	if err == nil {
+		encoders = parseEncoders(out)
+	}
+	fmt.Fprintln(w, "wav  可用（内置编码，不需要 ffmpeg）")
+	for _, f := range []string{"mp3", "ogg"} {
+		enc := formatEncoders[f]
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "%s  不可用：无法运行 ffmpeg (%v)\n", f, err)
+		case encoders[enc]:
+			fmt.Fprintf(w, "%s  可用（%s）\n", f, enc)
+		default:
+			fmt.Fprintf(w, "%s  不可用：ffmpeg 缺少 %s 编码器\n", f, enc)
+		}
+	}
+}
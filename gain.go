@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-audio/audio"
+)
+
+// parseGainList 解析 `key:dB,key:dB` 形式的增益列表
+func parseGainList(s string) (map[string]float64, error) {
+	gains := make(map[string]float64)
+	if s == "" {
+		return gains, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("无效的增益 %q，应为 key:dB", item)
+		}
+		db, err := strconv.ParseFloat(strings.TrimSpace(item[i+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的增益 %q: %v", item, err)
+		}
+		gains[strings.TrimSpace(item[:i])] = db
+	}
+	return gains, nil
+}
+
+// applyClipGain 把 -pre-gain 与片段 key 在 -gain、-duck 中的分贝数叠加后一次性应用，
+// 只取整和钳制一次；三者都未设置时不改动 buf
+func applyClipGain(buf *audio.IntBuffer, key string, preGain float64, gains, ducks map[string]float64) {
+	gain, hasGain := gains[key]
+	duck, hasDuck := ducks[key]
+	if hasGain || hasDuck || preGain != 0 {
+		applyGain(buf, dbToLinear(preGain+gain+duck))
+	}
+}
+
+// applyGain 把 buf 的采样乘以线性增益 factor，超出位深范围的值被钳制以防回绕
+func applyGain(buf *audio.IntBuffer, factor float64) {
+	bits := buf.SourceBitDepth
+	maxVal := float64(int(1)<<(bits-1) - 1)
+	minVal := -float64(int(1) << (bits - 1))
+	offset := 0
+	if bits == 8 {
+		offset = 128
+	}
+	for i, v := range buf.Data {
+		f := math.Round(float64(v-offset) * factor)
+		if f > maxVal {
+			f = maxVal
+		} else if f < minVal {
+			f = minVal
+		}
+		buf.Data[i] = int(f) + offset
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestApplyGainMinus6dBHalves(t *testing.T) {
+	buf := monoBuffer(16, 20000, -20000, 1000, 0)
+	applyGain(buf, dbToLinear(-6))
+	for i, in := range []int{20000, -20000, 1000, 0} {
+		// -6dB 的线性增益约为 0.501，允许 1% 的误差
+		want := float64(in) / 2
+		if math.Abs(float64(buf.Data[i])-want) > math.Abs(want)*0.01 {
+			t.Errorf("applyGain(%d, -6dB) = %d, want ≈ %g", in, buf.Data[i], want)
+		}
+	}
+}
+
+func TestApplyGainClamps(t *testing.T) {
+	tests := []struct {
+		name     string
+		bits     int
+		in, want []int
+	}{
+		{"16 位", 16, []int{20000, -20000, 100}, []int{32767, -32768, 200}},
+		{"24 位", 24, []int{5000000, -5000000}, []int{8388607, -8388608}},
+		// 8 位无符号：以 128 为零点放大后钳制到 0..255
+		{"8 位", 8, []int{200, 50, 130}, []int{255, 0, 132}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := monoBuffer(tt.bits, append([]int(nil), tt.in...)...)
+			applyGain(buf, 2)
+			if !reflect.DeepEqual(buf.Data, tt.want) {
+				t.Errorf("applyGain(%v, ×2) = %v, want %v", tt.in, buf.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGainList(t *testing.T) {
+	got, err := parseGainList("a:-6, b:3.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"a": -6, "b": 3.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGainList = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"a", ":3", "a:x"} {
+		if _, err := parseGainList(bad); err == nil {
+			t.Errorf("parseGainList(%q) 应返回错误", bad)
+		}
+	}
+}
+
+func TestApplyClipGainCombined(t *testing.T) {
+	gains := map[string]float64{"a": 12}
+	ducks := map[string]float64{"a": -3}
+	// -6 + 12 - 3 = +3dB，线性增益约 1.4125
+	buf := monoBuffer(16, 10000, -10000, 20000, 30000, -30000, 0)
+	applyClipGain(buf, "a", -6, gains, ducks)
+	factor := dbToLinear(3)
+	want := []int{
+		int(math.Round(10000 * factor)),
+		int(math.Round(-10000 * factor)),
+		// 分步应用时 +12dB 会先钳制到满幅，合并后只缩放一次
+		int(math.Round(20000 * factor)),
+		32767,
+		-32768,
+		0,
+	}
+	if !reflect.DeepEqual(buf.Data, want) {
+		t.Errorf("applyClipGain = %v, want %v", buf.Data, want)
+	}
+
+	// 没有 -gain/-duck 且 -pre-gain 为 0 的片段保持原样
+	buf = monoBuffer(16, 12345, -1)
+	applyClipGain(buf, "b", 0, gains, ducks)
+	if !reflect.DeepEqual(buf.Data, []int{12345, -1}) {
+		t.Errorf("未设置增益的片段被改为 %v", buf.Data)
+	}
+}
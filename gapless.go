@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-audio/audio"
+)
+
+// concatLedger 记录拼接时写入输出缓冲的帧数，供 -verify-gapless 核对
+type concatLedger struct {
+	// clipFrames 是各片段源数据的帧数之和，-dedup 复用已有区间的片段不计
+	clipFrames int
+	// gapFrames 是 -start-offset 与 -min-gap 插入的静音帧数
+	gapFrames int
+}
+
+// verifyConcat 核对拼接结果：片段帧数加上插入的静音应恰好等于输出缓冲的总帧数，
+// 且每个片段的区间长度等于其源数据帧数，否则说明边界处丢失或重复了采样
+func verifyConcat(ledger concatLedger, buf *audio.IntBuffer, sprites []sprite) error {
+	var errs []error
+	total := len(buf.Data) / buf.Format.NumChannels
+	if ledger.clipFrames+ledger.gapFrames != total {
+		errs = append(errs, fmt.Errorf("片段 %d 帧 + 静音 %d 帧 = %d 帧，输出缓冲却有 %d 帧",
+			ledger.clipFrames, ledger.gapFrames, ledger.clipFrames+ledger.gapFrames, total))
+	}
+	for _, sp := range sprites {
+		if sp.end-sp.start != sp.frames {
+			errs = append(errs, fmt.Errorf("%s: 区间 [%d, %d) 共 %d 帧，源数据为 %d 帧", sp.key, sp.start, sp.end, sp.end-sp.start, sp.frames))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifySpritemapFrames 核对写出的秒数：start/end 乘以 rate 取整后
+// 应回到片段在所在音频文件中的起止帧，(end-start)*rate 与区间帧数一致
+func verifySpritemapFrames(spritemap map[string]SpriteMapEntry, sprites []sprite, parts []atlasPart, rate int) error {
+	var errs []error
+	for _, sp := range sprites {
+		entry := spritemap[sp.key]
+		offset := parts[sp.resource].start
+		start := int(math.Round(entry.Start * float64(rate)))
+		end := int(math.Round(entry.End * float64(rate)))
+		if start != sp.start-offset || end != sp.end-offset {
+			errs = append(errs, fmt.Errorf("%s: start/end %g/%g 秒换算为帧 [%d, %d)，应为 [%d, %d)",
+				sp.key, entry.Start, entry.End, start, end, sp.start-offset, sp.end-offset))
+		}
+	}
+	return errors.Join(errs...)
+}
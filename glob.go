@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// globFold 与 filepath.Glob 相同，但逐级扫描目录并忽略大小写比较，
+// 用于在大小写敏感的文件系统上匹配 *.WAV 之类的文件
+func globFold(pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	dir, file := filepath.Split(pattern)
+	if file == "." || file == ".." {
+		return []string{pattern}, nil
+	}
+
+	var dirs []string
+	switch {
+	case dir == "":
+		dirs = []string{""}
+	case dir == string(filepath.Separator):
+		dirs = []string{dir}
+	default:
+		var err error
+		dirs, err = globFold(strings.TrimSuffix(dir, string(filepath.Separator)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lower := strings.ToLower(file)
+	var matches []string
+	for _, d := range dirs {
+		scan := d
+		if scan == "" {
+			scan = "."
+		}
+		entries, err := os.ReadDir(scan)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if ok, _ := filepath.Match(lower, strings.ToLower(e.Name())); ok {
+				matches = append(matches, filepath.Join(d, e.Name()))
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// expandBraces 像 shell 一样展开模式中的 {a,b} 备选项，支持嵌套，
+// 按出现顺序返回展开后的模式。没有逗号的 {a}、不成对的括号和
+// 以反斜杠转义的 \{ \} \, 保持原样
+func expandBraces(pattern string) []string {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+			continue
+		case '{':
+		default:
+			continue
+		}
+		end, alts := braceAlternatives(pattern, i)
+		if end < 0 || len(alts) < 2 {
+			continue
+		}
+		var out []string
+		for _, alt := range alts {
+			out = append(out, expandBraces(pattern[:i]+alt+pattern[end+1:])...)
+		}
+		return out
+	}
+	return []string{pattern}
+}
+
+// braceAlternatives 找到 pattern[open] 处 { 对应的 }，返回其下标和按顶层逗号
+// 切分的备选项；没有对应的 } 时返回 -1
+func braceAlternatives(pattern string, open int) (int, []string) {
+	depth := 0
+	start := open + 1
+	var alts []string
+	for i := open; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, append(alts, pattern[start:i])
+			}
+		case ',':
+			if depth == 1 {
+				alts = append(alts, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return -1, nil
+}
+
+// expandInputArgs 把命令行上的输入参数展开为片段：先拆出 file.wav@1.2-3.4 这样的
+// 裁剪区间，再展开 {a,b} 并匹配通配符，压缩包展开为其中的音频。
+// 同一参数匹配到的同一文件只取一次；没有匹配到任何文件时返回错误
+func expandInputArgs(args []string, ignoreCase bool) ([]clipSpec, error) {
+	glob := filepath.Glob
+	if ignoreCase {
+		glob = globFold
+	}
+	var inputs []clipSpec
+	for _, arg := range args {
+		pattern, trim, err := splitClipRange(arg)
+		if err != nil {
+			return nil, err
+		}
+		var matched []string
+		seenPath := make(map[string]bool)
+		for _, p := range expandBraces(pattern) {
+			m, err := glob(p)
+			if err != nil {
+				return nil, fmt.Errorf("无效的模式 %s: %v", p, err)
+			}
+			for _, path := range m {
+				if !seenPath[path] {
+					seenPath[path] = true
+					matched = append(matched, path)
+				}
+			}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("没有匹配到任何文件: %s", pattern)
+		}
+		for _, m := range matched {
+			if isArchive(m) {
+				specs, err := archiveInputs(m)
+				if err != nil {
+					return nil, fmt.Errorf("读取压缩包 %s 失败: %v", m, err)
+				}
+				for i := range specs {
+					specs[i].trim = trim
+				}
+				inputs = append(inputs, specs...)
+				continue
+			}
+			inputs = append(inputs, clipSpec{path: m, trim: trim})
+		}
+	}
+	return inputs, nil
+}
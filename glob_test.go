@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"a.wav", []string{"a.wav"}},
+		{"{a,b}.wav", []string{"a.wav", "b.wav"}},
+		{"x{a,b}y{1,2}", []string{"xay1", "xay2", "xby1", "xby2"}},
+		// 嵌套
+		{"{a,b{1,2}}.wav", []string{"a.wav", "b1.wav", "b2.wav"}},
+		{"{{a,b},c}", []string{"a", "b", "c"}},
+		// 空备选项
+		{"a{,_loop}.wav", []string{"a.wav", "a_loop.wav"}},
+		// 没有逗号的 {a} 保持原样
+		{"{a}.wav", []string{"{a}.wav"}},
+		{"{}.wav", []string{"{}.wav"}},
+		// 转义的括号和逗号不参与展开
+		{`\{a,b\}.wav`, []string{`\{a,b\}.wav`}},
+		{`{a\,b,c}.wav`, []string{`a\,b.wav`, "c.wav"}},
+		// 不成对的括号保持原样，后面成对的部分照常展开
+		{"{a,b.wav", []string{"{a,b.wav"}},
+		{"a,b}.wav", []string{"a,b}.wav"}},
+		{"{x{a,b}", []string{"{xa", "{xb"}},
+	}
+	for _, tt := range tests {
+		if got := expandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestGlobFold(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"A.WAV", "b.wav", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := globFold(filepath.Join(dir, "*.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "A.WAV"), filepath.Join(dir, "b.wav")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("globFold = %v, want %v", got, want)
+	}
+}
+
+func TestDashInputAfterSeparator(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("-intro.wav", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("go-audiosprite", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("o", "output", "")
+	// 没有 -- 时 -intro.wav 被当作未定义的选项
+	if err := fs.Parse([]string{"-o", "out", "-intro.wav"}); err == nil {
+		t.Fatal("-intro.wav 不在 -- 之后应被当作选项解析而报错")
+	}
+	if err := fs.Parse([]string{"-o", "out", "--", "-intro.wav"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := expandInputArgs(fs.Args(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].path != "-intro.wav" {
+		t.Errorf("输入 = %+v, want [-intro.wav]", got)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// spriteIndex 是 -index 输出的总索引，把各次构建的键映射到所在的 JSON 和音频，
+// 供加载器按需加载对应的 sprite。路径相对索引文件所在目录
+type spriteIndex struct {
+	Sprites map[string]indexEntry `json:"sprites"`
+}
+
+type indexEntry struct {
+	Manifest string `json:"manifest"`
+	Resource string `json:"resource"`
+}
+
+// updateIndex 把本次构建的键合并进 path 处的索引：先删掉指向同一 manifest 的旧条目，
+// 再写入当前的键；与其他 manifest 的键重名时给出警告并以本次为准
+func updateIndex(path, manifest string, spritemap map[string]SpriteMapEntry, resources []string, style string) error {
+	index := spriteIndex{Sprites: make(map[string]indexEntry)}
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &index); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if index.Sprites == nil {
+			index.Sprites = make(map[string]indexEntry)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	rel := func(p string) string {
+		if r, err := filepath.Rel(dir, p); err == nil {
+			p = r
+		}
+		return filepath.ToSlash(p)
+	}
+	manifestRel := rel(manifest)
+	for key, entry := range index.Sprites {
+		if entry.Manifest == manifestRel {
+			delete(index.Sprites, key)
+		}
+	}
+	for key, entry := range spritemap {
+		if old, ok := index.Sprites[key]; ok {
+			log.Printf("警告: 索引中的键 %s 已属于 %s，改为指向 %s", key, old.Manifest, manifestRel)
+		}
+		index.Sprites[key] = indexEntry{Manifest: manifestRel, Resource: rel(resources[entry.Resource])}
+	}
+
+	out, err := marshalManifest(index, style)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0644)
+}
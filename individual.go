@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/go-audio/audio"
+)
+
+// frameSlice 返回 buf 中 [start, end) 帧的视图，与 buf 共用采样数据
+func frameSlice(buf *audio.IntBuffer, start, end int) *audio.IntBuffer {
+	ch := buf.Format.NumChannels
+	return &audio.IntBuffer{
+		Format:         buf.Format,
+		Data:           buf.Data[start*ch : end*ch],
+		SourceBitDepth: buf.SourceBitDepth,
+	}
+}
+
+// writeIndividualFiles 把每个片段单独编码为 dir/<key>.<格式>，供单独试听或对比；
+// 格式取片段自身的 format，未指定时为 format，编码参数由 optsFor 按格式给出。
+// 编码失败返回 ffmpeg 的错误，写文件失败返回的错误包含路径
+func writeIndividualFiles(ctx context.Context, dir string, buf *audio.IntBuffer, sprites []sprite, rate int, format string, optsFor func(format string) convertOptions) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, sp := range sprites {
+		f := format
+		if sp.format != "" {
+			f = sp.format
+		}
+		data, err := encodeAudioBytes(ctx, frameSlice(buf, sp.start, sp.end), rate, f, optsFor(f))
+		if err != nil {
+			return err
+		}
+		out := filepath.Join(dir, sp.key+"."+f)
+		addOutput(out)
+		if err := ioutil.WriteFile(out, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
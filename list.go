@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clipSpec 描述一个待拼接的输入片段
+type clipSpec struct {
+	path string
+	// archive/entry 非空时片段来自 zip 压缩包中的条目，path 形如 `sounds.zip:ui/click.wav`
+	archive string
+	entry   string
+	// hasLoop 为 true 时 loop 由列表显式指定，覆盖 -loops
+	hasLoop bool
+	loop    bool
+	// loopRegion 非空时只循环片段中的这一段
+	loopRegion *loopRegion
+	// trim 非空时只取源文件中的这一段
+	trim *clipRange
+	// format 非空时片段输出为该格式，与其他格式的片段分到不同的音频文件
+	format string
+	// key 非空时为 -key-from 生成的键名
+	key string
+	// fadeIn/fadeOut 非空时为清单指定的淡入/淡出秒数，覆盖 -fadein/-fadeout
+	fadeIn  *float64
+	fadeOut *float64
+}
+
+// name 返回用于生成键名和匹配 -loops 的文件路径，压缩包条目取条目名
+func (c clipSpec) name() string {
+	if c.entry != "" {
+		return c.entry
+	}
+	return c.path
+}
+
+// clipKey 返回片段的键名：默认为文件名去掉扩展名
+func (c clipSpec) clipKey() string {
+	if c.key != "" {
+		return c.key
+	}
+	return fileKey(c.name())
+}
+
+// loopRegion 是相对片段起点的循环区间（秒）
+type loopRegion struct {
+	start, end float64
+}
+
+// validate 检查 0 <= start < end <= duration
+func (r *loopRegion) validate(duration float64) error {
+	if r.start < 0 || r.start >= r.end || r.end > duration {
+		return fmt.Errorf("循环区间 [%g, %g] 无效，需满足 0 <= loopStart < loopEnd <= %g", r.start, r.end, duration)
+	}
+	return nil
+}
+
+// readListFile 读取制表符分隔的输入列表，每行为 `path<TAB>loop`，
+// loop 列可省略；以 # 开头的行和空行被忽略。
+// 相对路径以列表文件所在目录为基准。
+func readListFile(path string) ([]clipSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var specs []clipSpec
+	dir := filepath.Dir(path)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		spec := clipSpec{path: strings.TrimSpace(fields[0])}
+		if !filepath.IsAbs(spec.path) {
+			spec.path = filepath.Join(dir, spec.path)
+		}
+		if len(fields) > 1 && strings.TrimSpace(fields[1]) != "" {
+			loop, err := strconv.ParseBool(strings.TrimSpace(fields[1]))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: 无效的 loop 值 %q", path, lineNo, fields[1])
+			}
+			spec.hasLoop = true
+			spec.loop = loop
+		}
+		specs = append(specs, spec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// source 返回用于 -include-sources 的源文件路径：相对 root（为空时取当前目录），
+// 统一使用 /，压缩包条目写为 压缩包路径:条目名
+func (c clipSpec) source(root string) string {
+	path := c.path
+	if c.archive != "" {
+		path = c.archive
+	}
+	if root == "" {
+		root = "."
+	}
+	absRoot, err1 := filepath.Abs(root)
+	absPath, err2 := filepath.Abs(path)
+	if err1 == nil && err2 == nil {
+		if rel, err := filepath.Rel(absRoot, absPath); err == nil {
+			path = rel
+		}
+	}
+	path = filepath.ToSlash(path)
+	if c.archive != "" {
+		path += ":" + c.entry
+	}
+	return path
+}
+
+// groupByFormat 把输入按输出格式稳定分组，各组按首次出现的顺序排列，
+// 未指定格式的片段使用 def。返回是否出现了不止一种格式
+func groupByFormat(inputs []clipSpec, def string) bool {
+	order := make(map[string]int)
+	for i := range inputs {
+		if inputs[i].format == "" {
+			inputs[i].format = def
+		}
+		if _, ok := order[inputs[i].format]; !ok {
+			order[inputs[i].format] = len(order)
+		}
+	}
+	sort.SliceStable(inputs, func(i, j int) bool {
+		return order[inputs[i].format] < order[inputs[j].format]
+	})
+	return len(order) > 1
+}
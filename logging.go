@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+)
+
+// quiet 对应 -quiet：不输出进度和完成提示，警告与错误照常写到 stderr
+var quiet bool
+
+// verbose 对应 -verbose：额外输出调试信息
+var verbose bool
+
+// debugf 输出调试信息，仅在 -verbose 时输出
+func debugf(format string, v ...interface{}) {
+	if verbose {
+		log.Printf(format, v...)
+	}
+}
+
+// infof 输出进度类信息，-quiet 时省略
+func infof(format string, v ...interface{}) {
+	if !quiet {
+		log.Printf(format, v...)
+	}
+}
+
+// donef 向 w 输出完成提示，-quiet 时省略
+func donef(w io.Writer, format string, v ...interface{}) {
+	if !quiet {
+		fmt.Fprintf(w, format, v...)
+	}
+}
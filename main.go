@@ -1,14 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/go-audio/audio"
@@ -18,117 +26,1195 @@ import (
 type SpriteMapEntry struct {
 	Start float64 `json:"start"`
 	End   float64 `json:"end"`
-	Loop  bool    `json:"loop"`
+	// Loop 在 -no-loop-in-export 时为 nil，不输出
+	Loop *bool `json:"loop,omitempty"`
+	// LoopStart/LoopEnd 是相对片段起点的循环区间（秒），未指定时整个片段循环
+	LoopStart *float64 `json:"loopStart,omitempty"`
+	LoopEnd   *float64 `json:"loopEnd,omitempty"`
+	// StartMs/EndMs 是 -export both 时额外输出的整数毫秒
+	StartMs *int64 `json:"startMs,omitempty"`
+	EndMs   *int64 `json:"endMs,omitempty"`
+	// StartUnits/EndUnits 是 -time-base N 时额外输出的 1/N 秒整数单位
+	StartUnits *int64 `json:"startUnits,omitempty"`
+	EndUnits   *int64 `json:"endUnits,omitempty"`
+	// DurationPct 是 -stats 时输出的时长占比（%）
+	DurationPct *float64 `json:"durationPct,omitempty"`
+	// Resource 是片段所在音频在 Resources 中的下标，Start/End 相对该文件
+	Resource int `json:"resource,omitempty"`
+	// Source 是片段的源文件路径（相对 -source-root），仅在 -include-sources 时输出
+	Source string `json:"source,omitempty"`
+	// Meta 是 -merge-sidecar 时从 <file>.json 原样复制的字段，放在子对象中，不覆盖上面的任何字段
+	Meta map[string]json.RawMessage `json:"meta,omitempty"`
 }
 
 type SpriteJSON struct {
+	// SpriteID 是 -sprite-id 时输出的构建参数哈希，见 spriteID
+	SpriteID string `json:"spriteId,omitempty"`
+	// TimeBase 是 -time-base 的 N，说明 startUnits/endUnits 的单位
+	TimeBase  int                       `json:"timeBase,omitempty"`
 	Resources []string                  `json:"resources"`
 	Spritemap map[string]SpriteMapEntry `json:"spritemap"`
 }
 
+// sprite 记录一个片段在输出缓冲中的帧区间，按追加顺序保存
+type sprite struct {
+	key        string
+	start, end int
+	loop       bool
+	loopRegion *loopRegion
+	resource   int
+	// source 是片段的源文件路径，见 clipSpec.source
+	source string
+	// format 是片段所在音频文件的输出格式
+	format string
+	// frames 是追加时源数据的帧数，-split-silence 生成的片段为 0
+	frames int
+	// meta 是 -merge-sidecar 读到的 sidecar 字段
+	meta map[string]json.RawMessage
+	// padded 表示片段已由 -fixed-length 补齐，-trim-end 不裁剪它
+	padded bool
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verifyCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		mergeCommand(os.Args[2:])
+		return
+	}
+
 	outBase := flag.String("o", "sprite", "输出文件基名（不含扩展名）")
-	loopList := flag.String("loops", "", "默认循环的文件名列表，用逗号分隔")
-	formatFlag := flag.String("format", "wav", "输出音频格式，可选: wav, mp3, ogg")
+	loopList := flag.String("loops", "", "默认循环的文件名列表，用逗号分隔；也可写文件模式，如 loops/*.wav")
+	formatFlag := flag.String("format", "wav", "输出音频格式，可选: wav, mp3, ogg；逗号分隔多个时同一 sprite 输出为每种格式，如 mp3,ogg")
+	bitsFlag := flag.Int("bits", 0, "输出位深，可选: 8, 16, 24；默认沿用第一个输入文件")
+	trimEnd := flag.Bool("trim-end", false, "裁掉输出末尾最后一个片段尾部的静音并缩短其 end（循环片段及 -fixed-length 补齐的片段不裁）")
+	rateFlag := flag.Int("rate", 0, "输出采样率，不一致的输入会被重采样；默认沿用第一个输入文件")
+	noJSON := flag.Bool("no-json", false, "只输出音频，不写出 JSON")
+	printMode := flag.String("print", "", "构建完成后输出到 stdout 的内容，可选: summary")
+	reverseSuffix := flag.String("reverse-suffix", "", "非空时为每个输入额外追加倒放片段，键名为 原键名+后缀")
+	gainList := flag.String("gain", "", "按片段调整音量，格式 key:dB，用逗号分隔，如 click:-6,boom:3")
+	keepWAV := flag.Bool("keep-wav", false, "转换为 mp3/ogg 后保留中间 WAV，并加入 resources")
+	waveformOut := flag.String("waveform", "", "把整个 sprite 的波形图写入指定 PNG，片段起点以竖线标出")
+	minGap := flag.Float64("min-gap", 0, "相邻片段之间至少间隔的秒数，不足时补静音")
+	maxFileSize := flag.String("max-file-size", "", "单个音频文件的估算大小上限（如 500K、2M），超出时拆分为 基名_0、基名_1 …")
+	ignoreCase := flag.Bool("ignore-case", false, "匹配输入模式时忽略大小写（如 *.wav 也匹配 *.WAV）")
+	manifestFile := flag.String("manifest", "", "从 JSON 构建清单读取输入及片段属性（loop、loopStart、loopEnd）")
+	flattenMono := flag.Bool("flatten-mono", false, "把多声道输入按 (L+R)/2 混合为单声道，输出单声道 sprite")
+	strictRate := flag.Bool("strict-rate", false, "输入采样率与目标不一致时报错退出，而不是重采样")
+	playlist := flag.String("playlist", "", "额外写出 CUE 播放列表，每个片段一条音轨，便于在播放器中跳转试听")
+	alignMode := flag.String("align-frames", "error", "采样数不是声道数整数倍时的处理方式，可选: error, pad")
+	exportFlag := flag.String("export", "seconds", "逗号分隔的导出格式: seconds, both（JSON 同时输出 startMs/endMs），howler, createjs, jukebox（额外写出 基名.<格式>.json）")
+	cpuProfile := flag.String("cpuprofile", "", "把 CPU profile 写入指定文件")
+	memProfile := flag.String("memprofile", "", "构建结束后把堆内存 profile 写入指定文件")
+	sortMode := flag.String("sort", "", "输入排序方式，可选: name, natural；默认保持参数顺序")
+	stats := flag.Bool("stats", false, "在 JSON 中输出每个片段的时长占比 durationPct")
+	title := flag.String("title", "", "写入 mp3/ogg 标签的标题")
+	author := flag.String("author", "", "写入 mp3/ogg 标签的作者")
+	comment := flag.String("comment", "", "写入 mp3/ogg 标签的备注")
+	splitSilence := flag.Bool("split-silence", false, "按静音把拼接结果自动切分为 clip_0、clip_1 … 片段")
+	splitThreshold := flag.Int("split-threshold", 0, "切分时视为静音的最大采样幅度，0 表示使用 -trim-threshold-dbfs")
+	splitMinSilence := flag.Float64("split-min-silence", 0.1, "切分所需的最短连续静音（秒）")
+	nameTemplate := flag.String("name-template", defaultNameTemplate, "自动生成键名的模板，支持 {base}、{index}、{rate}，可带格式如 {index:03d}")
+	commandLogFile := flag.String("command-log", "", "把本次执行的外部命令（ffmpeg、-preview 的 ffplay）及退出状态写入指定 JSON 文件")
+	dedup := flag.Bool("dedup", false, "内容完全相同的片段只写入一次，多个键共用同一区间")
+	templateFile := flag.String("template", "", "用 Go text/template 模板生成自定义格式的清单")
+	templateOut := flag.String("template-out", "", "模板输出路径，默认为 基名+模板文件去掉 .gotmpl 后的扩展名")
+	oggQuality := flag.Float64("ogg-quality", 0, "ogg 输出的 libvorbis 质量 (-1 到 10)，直接作为 -qscale:a 传给 ffmpeg")
+	roundMode := flag.String("round", "", "按播放器换算回帧的取整方式微调 start/end 秒数: nearest, floor, ceil（默认输出原始浮点）")
+	noLoopInExport := flag.Bool("no-loop-in-export", false, "导出的清单中不包含 loop/loopStart/loopEnd（片段本身是否循环不受影响）")
+	rateFrom := flag.String("rate-from", "", "以指定输入文件（文件名或路径）的采样率作为输出采样率，其余输入重采样到该值")
+	downmixSurround := flag.Bool("downmix-surround", false, "把 5.1/7.1 输入按 ITU 系数下混为立体声（配合 -flatten-mono 时为单声道）")
+	formatLoudness := flag.String("format-loudness", "", "按输出格式补偿编码器造成的响度差异，格式为 mp3:dB,ogg:dB，在 ffmpeg 转换时应用")
+	reportOut := flag.String("report", "", "生成自包含的 HTML 报告，列出每个片段的时间范围和循环标记；启用 -waveform 时附带波形缩略图")
+	duckList := flag.String("duck", "", "压低指定片段的音量，格式 key:dB（dB 不大于 0），与 -gain 叠加")
+	checkClipping := flag.Bool("check-clipping", false, "检查输入中接近满幅的采样，占比过高时给出警告")
+	startOffset := flag.Float64("start-offset", 0, "在第一个片段前插入的静音秒数，所有片段的时间随之后移")
+	checkLayout := flag.Bool("check-layout", false, "检查双声道输入是否像是按声道平铺（planar）存储却被当作交错数据读出，可疑时给出警告")
+	jsonPretty := flag.Bool("json-pretty", true, "输出缩进的 JSON；为 false 时等同 -json-style compact")
+	jsonStyle := flag.String("json-style", "", "JSON 输出风格: pretty, compact（无空白的单行）, escaped（单行后再转义为 Go/JS 字符串字面量）；默认按 -json-pretty，两者矛盾时报错")
+	includeSources := flag.Bool("include-sources", false, "在 JSON 的每个条目中写入源文件路径 source")
+	sourceRoot := flag.String("source-root", "", "source 路径相对的根目录，默认为当前目录")
+	individualDir := flag.String("also-individual", "", "额外把每个片段单独编码为 目录/<key>.<格式>，便于单独试听")
+	quietFlag := flag.Bool("quiet", false, "不输出进度和完成提示，警告与错误仍写到 stderr")
+	preGain := flag.Float64("pre-gain", 0, "对所有输入统一衰减的分贝数（不大于 0），与 -gain/-duck 叠加后一次应用")
+	intermediateBits := flag.Int("intermediate-bits", 0, "输出 mp3/ogg 时交给 ffmpeg 的中间 WAV 位深，可选: 16, 24, 32；默认同 -bits")
+	removeDC := flag.Bool("remove-dc", false, "减去每个片段各声道的直流偏移（采样均值），避免拼接处出现咔哒声")
+	onCollision := flag.String("on-collision", collisionError, "多个片段生成相同键名时的处理方式: error, skip（保留先出现的）, replace（保留后出现的）")
+	indexFile := flag.String("index", "", "把本次的键合并进总索引 JSON（键 → 所在的 JSON 和音频），多次构建共用同一个索引")
+	trimThreshold := flag.Float64("trim-threshold-dbfs", silenceDBFS, "-trim-end 与 -split-silence 视为静音的电平（dBFS），按输出位深的满幅换算")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "同时运行的 ffmpeg 转换数")
+	retriesFlag := flag.Int("ffmpeg-retries", 0, "ffmpeg 因启动失败、被系统终止或资源不足等临时原因失败时的重试次数，编码错误不重试")
+	verboseFlag := flag.Bool("verbose", false, "输出调试信息，如 ffmpeg 的重试")
+	verifyGapless := flag.Bool("verify-gapless", false, "构建后核对帧数：片段与插入的静音之和等于输出总帧数，每个片段的 start/end 换算回帧与源数据一致")
+	keyFrom := flag.String("key-from", keyFromName, "键名来源: name（文件名去掉扩展名）, midi（文件名开头的 MIDI 音符号，如 060.wav）")
+	midiKeyTemplate := flag.String("midi-key-template", defaultMIDIKeyTemplate, "-key-from midi 的键名模板，支持 {note}、{base}，可带格式如 {note:03d}")
+	limit := flag.Int("limit", 0, "只使用排序后的前 N 个输入，便于快速试构建；0 表示不限制")
+	fadeIn := flag.Float64("fadein", 0, "每个片段开头的线性淡入秒数，清单中的 fadein 优先")
+	fadeOut := flag.Float64("fadeout", 0, "每个片段末尾的线性淡出秒数，清单中的 fadeout 优先")
+	padToSecond := flag.Bool("pad-to-second", false, "在末尾补静音，使整个输出的总时长为整数秒（在 -trim-end 之后进行）")
+	jsonRootKey := flag.String("json-root-key", "", "非空时把主 JSON 包在该键下，如 v1 输出 {\"v1\": {...}}；-export 的额外清单不受影响")
+	formatSubdirs := flag.Bool("format-subdirs", false, "每种格式的音频写入输出目录下以格式命名的子目录，如 mp3/sprite.mp3、ogg/sprite.ogg；JSON 中的 resources 相对 JSON 所在目录")
+	noResample := flag.Bool("no-resample", false, "采样率不一致的输入不重采样，原样拼接（音调会变），只用于快速试听")
+	embed := flag.Bool("embed", false, "把音频以 base64 data URI 内嵌进 JSON 的 resources（含 -export 的额外清单），音频文件照常写出")
+	forceStereo := flag.Bool("force-stereo", false, "把单声道输入的每个采样复制到左右声道，输出立体声 sprite（不经过 ffmpeg）")
+	withSpriteID := flag.Bool("sprite-id", false, "在 JSON 中输出 spriteId：由输入文件顺序、拼接后的音频、清单内容和编码参数算出的稳定哈希")
+	resampleQuality := flag.String("resample-quality", "default", "ffmpeg 重采样质量: default（ffmpeg 默认）, high（soxr 精度 20）, veryhigh（soxr 精度 28，更慢）")
+	listFormatsFlag := flag.Bool("list-formats", false, "检查 ffmpeg 的编码器，列出本机可用的输出格式后退出")
+	channelsFlag := flag.Int("channels", 0, "输出声道数，可选: 1（同 -flatten-mono）, 2（同 -force-stereo）；在内存中转换，不经过 ffmpeg。默认沿用第一个输入文件")
+	wavFact := flag.Bool("wav-fact", false, "在输出的 WAV 中写入可选的 fact 块（记录采样帧数）")
+	wavBlockAlign := flag.Int("wav-block-align", 0, "覆盖输出 WAV 文件头中的 BlockAlign，0 表示按声道数和位深计算")
+	wavByteRate := flag.Int("wav-byte-rate", 0, "覆盖输出 WAV 文件头中的 ByteRate，0 表示按采样率和 BlockAlign 计算")
+	timeBase := flag.Int("time-base", 0, "非 0 时在 JSON 中额外输出 startUnits/endUnits，单位为 1/N 秒（如 60 表示 60fps 的帧数）")
+	mergeSidecar := flag.Bool("merge-sidecar", false, "输入旁有 <文件>.json（如 click.wav.json）时，把其中的字段原样写入该片段条目的 meta")
+	allowTruncated := flag.Bool("allow-truncated", false, "输入的 data 块声明长度与实际采样数不符（文件被截断）时只警告并使用已有部分，默认报错退出")
+	previewKey := flag.String("preview", "", "拼接完成后用 ffplay 播放指定键的片段试听，之后照常写出文件")
+	fixedLength := flag.Float64("fixed-length", 0, "非 0 时每个片段末尾补静音到该秒数，使所有片段的 end-start 相同；有片段更长时报错")
+	numbersAsStrings := flag.Bool("json-numbers-as-strings", false, "主 JSON 中的 start/end 以保留 6 位小数的字符串输出，如 \"0.340000\"")
+	listFile := flag.String("list", "", "从制表符分隔的列表文件读取输入，每行为 path<TAB>loop")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [选项] [--] 文件或模式...\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "      %s verify [选项] sprite.json...\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "      %s merge [选项] a.json b.json...\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(flag.CommandLine.Output(), "  -- 之后的参数一律视为输入模式，可用于以 - 开头的文件名")
+		fmt.Fprintf(flag.CommandLine.Output(), "  每个选项都可用环境变量设置默认值，如 -format 对应 %s，命令行优先\n", envName("format"))
+		flag.PrintDefaults()
+	}
+	if err := applyEnvDefaults(flag.CommandLine); err != nil {
+		fatalf(exitInput, "%v", err)
+	}
 	flag.Parse()
+	quiet = *quietFlag
+	if *listFormatsFlag {
+		listFormats(context.Background(), os.Stdout)
+		return
+	}
+	verbose = *verboseFlag
+	if *retriesFlag < 0 {
+		fatalf(exitInput, "无效的 -ffmpeg-retries: %d", *retriesFlag)
+	}
+	ffmpegRetries = *retriesFlag
 
+	// setFlags 记录命令行或环境变量显式设置过的选项
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	// -o 带有音频扩展名时去掉扩展名作为基名，并据此确定输出格式
+	if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(*outBase), ".")); outputFormats[ext] {
+		*outBase = strings.TrimSuffix(*outBase, filepath.Ext(*outBase))
+		if !setFlags["format"] {
+			*formatFlag = ext
+		} else if !strings.EqualFold(*formatFlag, ext) {
+			log.Printf("警告: -o 的扩展名 .%s 与 -format %s 不一致，以 -format 为准", ext, *formatFlag)
+		}
+	}
 	// 检查格式合法性
-	valid := map[string]bool{"wav": true, "mp3": true, "ogg": true}
-	if !valid[strings.ToLower(*formatFlag)] {
-		log.Fatalf("不支持的格式: %s，仅支持 wav, mp3, ogg", *formatFlag)
+	formats, err := parseFormatList(*formatFlag)
+	if err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+	if *jobs < 1 {
+		fatalf(exitInput, "无效的 -jobs: %d，至少为 1", *jobs)
+	}
+	if *printMode != "" && *printMode != "summary" {
+		fatalf(exitInput, "不支持的 -print 取值: %s，仅支持 summary", *printMode)
+	}
+	oggQualitySet := setFlags["ogg-quality"]
+	if oggQualitySet {
+		if *oggQuality < -1 || *oggQuality > 10 {
+			fatalf(exitInput, "-ogg-quality 超出范围: %g，应在 -1 到 10 之间", *oggQuality)
+		}
+		if !hasFormat(formats, "ogg") {
+			log.Printf("警告: 输出格式不是 ogg，已忽略 -ogg-quality")
+		}
+	}
+	switch *roundMode {
+	case roundRaw, roundNearest, roundFloor, roundCeil:
+	default:
+		fatalf(exitInput, "不支持的 -round 取值: %s，仅支持 nearest, floor, ceil", *roundMode)
+	}
+	if *sortMode != "" && *sortMode != "name" && *sortMode != "natural" {
+		fatalf(exitInput, "不支持的 -sort 取值: %s，仅支持 name, natural", *sortMode)
+	}
+	loudness, err := parseGainList(*formatLoudness)
+	if err != nil {
+		fatalf(exitInput, "-format-loudness: %v", err)
+	}
+	for f, db := range loudness {
+		if lf := strings.ToLower(f); lf != "mp3" && lf != "ogg" {
+			fatalf(exitInput, "-format-loudness 不支持格式 %s，仅支持 mp3, ogg", f)
+		} else if lf != f {
+			delete(loudness, f)
+			loudness[lf] = db
+		}
+	}
+	exportMode, extraFormats, err := parseExportList(*exportFlag)
+	if err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+	style, err := resolveJSONStyle(*jsonStyle, *jsonPretty, setFlags["json-pretty"])
+	if err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+	*jsonStyle = style
+	if *alignMode != "error" && *alignMode != "pad" {
+		fatalf(exitInput, "不支持的 -align-frames 取值: %s，仅支持 error, pad", *alignMode)
+	}
+	if *rateFlag < 0 {
+		fatalf(exitInput, "无效的采样率: %d", *rateFlag)
+	}
+	if *trimThreshold > 0 {
+		fatalf(exitInput, "无效的 -trim-threshold-dbfs: %g，应不大于 0", *trimThreshold)
+	}
+	if *wavBlockAlign < 0 || *wavBlockAlign > math.MaxUint16 || *wavByteRate < 0 || *wavByteRate > math.MaxUint32 {
+		fatalf(exitInput, "无效的 -wav-block-align/-wav-byte-rate")
+	}
+	wavHeader := wavHeaderOptions{fact: *wavFact, blockAlign: *wavBlockAlign, byteRate: *wavByteRate}
+	if *fixedLength < 0 {
+		fatalf(exitInput, "无效的 -fixed-length: %g", *fixedLength)
+	}
+	if *timeBase < 0 {
+		fatalf(exitInput, "无效的 -time-base: %d", *timeBase)
+	}
+	if *fadeIn < 0 || *fadeOut < 0 {
+		fatalf(exitInput, "-fadein/-fadeout 不能为负数")
+	}
+	if *limit < 0 {
+		fatalf(exitInput, "无效的 -limit: %d", *limit)
+	}
+	if *startOffset < 0 {
+		fatalf(exitInput, "无效的 -start-offset: %g", *startOffset)
+	}
+	switch *channelsFlag {
+	case 0:
+	case 1:
+		if *forceStereo {
+			fatalf(exitInput, "-channels 1 与 -force-stereo 不能同时使用")
+		}
+		*flattenMono = true
+	case 2:
+		if *flattenMono {
+			fatalf(exitInput, "-channels 2 与 -flatten-mono 不能同时使用")
+		}
+		*forceStereo = true
+	default:
+		fatalf(exitInput, "不支持的 -channels: %d，仅支持 1, 2", *channelsFlag)
+	}
+	if *forceStereo && *flattenMono {
+		fatalf(exitInput, "-force-stereo 与 -flatten-mono 不能同时使用")
+	}
+	if _, ok := resampleFilters[*resampleQuality]; !ok {
+		fatalf(exitInput, "不支持的 -resample-quality 取值: %s，仅支持 default, high, veryhigh", *resampleQuality)
+	}
+	if *noResample && *strictRate {
+		fatalf(exitInput, "-no-resample 与 -strict-rate 不能同时使用")
+	}
+	if *rateFlag != 0 && *rateFrom != "" {
+		fatalf(exitInput, "-rate 与 -rate-from 不能同时使用")
+	}
+	var sizeLimit int64
+	if *maxFileSize != "" {
+		limit, err := parseSize(*maxFileSize)
+		if err != nil {
+			fatalf(exitInput, "%v", err)
+		}
+		sizeLimit = limit
+	}
+	if *minGap < 0 {
+		fatalf(exitInput, "-min-gap 不能为负数")
+	}
+	if *bitsFlag != 0 && *bitsFlag != 8 && *bitsFlag != 16 && *bitsFlag != 24 {
+		fatalf(exitInput, "不支持的位深: %d，仅支持 8, 16, 24", *bitsFlag)
+	}
+	switch *intermediateBits {
+	case 0, 16, 24, 32:
+	default:
+		fatalf(exitInput, "不支持的 -intermediate-bits: %d，仅支持 16, 24, 32", *intermediateBits)
+	}
+	if *intermediateBits != 0 && len(formats) == 1 && formats[0] == "wav" {
+		log.Printf("警告: wav 输出没有中间文件，已忽略 -intermediate-bits")
+	}
+
+	if _, err := expandNameTemplate(*nameTemplate, nameVars("", 0, 0)); err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+	switch *keyFrom {
+	case keyFromName:
+	case keyFromMIDI:
+		if _, err := expandNameTemplate(*midiKeyTemplate, midiVars("", 0)); err != nil {
+			fatalf(exitInput, "%v", err)
+		}
+	default:
+		fatalf(exitInput, "不支持的 -key-from 取值: %s，仅支持 name, midi", *keyFrom)
 	}
 
-	var inputs []string
-	for _, pattern := range flag.Args() {
-		matched, err := filepath.Glob(pattern)
+	// 先确认输出目录可写，避免解码、重采样完才在写出时失败
+	if err := checkWritable(filepath.Dir(*outBase)); err != nil {
+		fatalf(exitIO, "输出目录不可写: %v", err)
+	}
+
+	var inputs []clipSpec
+	if *listFile != "" {
+		specs, err := readListFile(*listFile)
 		if err != nil {
-			log.Fatalf("无效的模式 %s: %v", pattern, err)
+			fatalf(exitInput, "读取列表 %s 失败: %v", *listFile, err)
 		}
-		if len(matched) == 0 {
-			log.Fatalf("没有匹配到任何文件: %s", pattern)
+		inputs = append(inputs, specs...)
+	}
+	if *manifestFile != "" {
+		specs, err := readManifest(*manifestFile)
+		if err != nil {
+			fatalf(exitInput, "读取清单 %s 失败: %v", *manifestFile, err)
 		}
-		inputs = append(inputs, matched...)
+		inputs = append(inputs, specs...)
+	}
+	// 参数中以 - 开头的文件名需放在 -- 之后，否则会被当作选项
+	specs, err := expandInputArgs(flag.Args(), *ignoreCase)
+	if err != nil {
+		fatalf(exitInput, "%v", err)
 	}
+	inputs = append(inputs, specs...)
 	if len(inputs) == 0 {
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitInput)
+	}
+	switch *sortMode {
+	case "name":
+		sort.SliceStable(inputs, func(i, j int) bool { return inputs[i].path < inputs[j].path })
+	case "natural":
+		sort.SliceStable(inputs, func(i, j int) bool { return naturalLess(inputs[i].path, inputs[j].path) })
+	}
+	if *limit > 0 && len(inputs) > *limit {
+		infof("-limit: 只使用 %d 个输入中的前 %d 个", len(inputs), *limit)
+		inputs = inputs[:*limit]
+	}
+	if *keyFrom == keyFromMIDI {
+		for i := range inputs {
+			key, err := midiKey(inputs[i].name(), *midiKeyTemplate)
+			if err != nil {
+				fatalf(exitInput, "%v", err)
+			}
+			inputs[i].key = key
+		}
+	}
+	if *embed && *noJSON {
+		fatalf(exitInput, "-embed 需要写出 JSON，不能与 -no-json 同时使用")
+	}
+	if *indexFile != "" && *noJSON {
+		fatalf(exitInput, "-index 需要写出 JSON，不能与 -no-json 同时使用")
+	}
+	switch *onCollision {
+	case collisionError, collisionSkip, collisionReplace:
+	default:
+		fatalf(exitInput, "不支持的 -on-collision 取值: %s，仅支持 error, skip, replace", *onCollision)
+	}
+	inputs, err = resolveInputCollisions(inputs, *onCollision)
+	if err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+
+	// 清单中为片段指定了不同的 format 时，同一格式的片段相邻排列，各自输出为单独的音频文件
+	format := formats[0]
+	grouped := groupByFormat(inputs, format)
+	if grouped && *splitSilence {
+		fatalf(exitInput, "-split-silence 不能与按片段指定的 format 同时使用")
+	}
+	if grouped && len(formats) > 1 {
+		fatalf(exitInput, "-format 指定多个格式时不能再按片段指定 format")
 	}
 
 	loops := make(map[string]bool)
 	if *loopList != "" {
 		for _, name := range strings.Split(*loopList, ",") {
-			loops[strings.TrimSpace(name)] = true
+			name = strings.TrimSpace(name)
+			// 带通配符的项按文件模式展开，匹配到的文件名都视为循环
+			if strings.ContainsAny(name, "*?[") {
+				glob := filepath.Glob
+				if *ignoreCase {
+					glob = globFold
+				}
+				matched, err := glob(name)
+				if err != nil {
+					fatalf(exitInput, "无效的 -loops 模式 %s: %v", name, err)
+				}
+				if len(matched) == 0 {
+					log.Printf("警告: -loops 模式 %s 没有匹配到任何文件", name)
+				}
+				for _, m := range matched {
+					loops[filepath.Base(m)] = true
+				}
+				continue
+			}
+			loops[name] = true
+		}
+	}
+
+	commandLog.path = *commandLogFile
+
+	stopProfiling, err := startProfiling(*cpuProfile, *memProfile)
+	if err != nil {
+		fatalf(exitIO, "启动 profile 失败: %v", err)
+	}
+
+	// Ctrl-C 或 SIGTERM 时取消 ctx，终止 ffmpeg 并清理临时与未完成的文件
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handleSignals(cancel)
+
+	gains, err := parseGainList(*gainList)
+	if err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+	ducks, err := parseGainList(*duckList)
+	if err != nil {
+		fatalf(exitInput, "-duck: %v", err)
+	}
+	if *preGain > 0 {
+		fatalf(exitInput, "-pre-gain 只能衰减音量，%gdB 应不大于 0", *preGain)
+	}
+	for key, db := range ducks {
+		if db > 0 {
+			fatalf(exitInput, "-duck 只能衰减音量，%s 的 %gdB 应不大于 0", key, db)
 		}
 	}
 
 	var outBuf *audio.IntBuffer
-	var targetRate int
+	targetRate := *rateFlag
+	if *rateFrom != "" {
+		in, ok := findInput(inputs, *rateFrom)
+		if !ok {
+			fatalf(exitInput, "-rate-from 指定的 %s 不在输入中", *rateFrom)
+		}
+		targetRate, err = inputSampleRate(ctx, in)
+		if err != nil {
+			fatalf(exitInput, "读取 %s 的采样率失败: %v", in.path, err)
+		}
+	}
+	targetBits := *bitsFlag
 	currentSample := 0
-	spritemap := make(map[string]SpriteMapEntry)
+	var sprites []sprite
+
+	// -dedup 时记录已写入内容的哈希及其区间
+	seen := make(map[[32]byte]sprite)
+	dedupSaved, dedupCount := 0, 0
+	var ledger concatLedger
+
+	// lastAppendedEnd 是最后一段实际写入输出缓冲的音频的结束帧，-1 表示尚未写入；
+	// -dedup 复用的片段指向更早的区间，不能用 sprites 的最后一项计算间隔
+	lastAppendedEnd := -1
+
+	// appendSprite 把一段采样追加到输出缓冲，并以 sp 为模板记录其帧区间；
+	// 与上一段写入的音频的间隔不足 -min-gap 时先补足静音
+	appendSprite := func(sp sprite, data []int) {
+		ch := outBuf.Format.NumChannels
+		sp.frames = len(data) / ch
+		var sum [32]byte
+		if *dedup {
+			sum = hashSamples(sp.format, data)
+			if prev, ok := seen[sum]; ok {
+				sp.start, sp.end = prev.start, prev.end
+				sprites = append(sprites, sp)
+				dedupSaved += len(data) * outBuf.SourceBitDepth / 8
+				dedupCount++
+				return
+			}
+		}
+		// -start-offset 的前导静音放在第一个片段之前
+		if len(sprites) == 0 && *startOffset > 0 {
+			lead := int(math.Round(*startOffset * float64(targetRate)))
+			outBuf.Data = append(outBuf.Data, silenceFrames(lead, ch, outBuf.SourceBitDepth)...)
+			currentSample += lead
+			ledger.gapFrames += lead
+		}
+		if lastAppendedEnd >= 0 && *minGap > 0 {
+			need := int(math.Ceil(*minGap*float64(targetRate))) - (currentSample - lastAppendedEnd)
+			if need > 0 {
+				outBuf.Data = append(outBuf.Data, silenceFrames(need, ch, outBuf.SourceBitDepth)...)
+				currentSample += need
+				ledger.gapFrames += need
+			}
+		}
+		sp.start = currentSample
+		outBuf.Data = append(outBuf.Data, data...)
+		currentSample += sp.frames
+		ledger.clipFrames += sp.frames
+		sp.end = currentSample
+		lastAppendedEnd = currentSample
+		sprites = append(sprites, sp)
+		if *dedup {
+			seen[sum] = sp
+		}
+	}
 
-	for _, infile := range inputs {
-		buf, err := decodeWAV(infile)
+	// checkAlignment 按 -align-frames 处理末尾不完整的帧；-flatten-mono 下混时
+	// 会丢弃这一帧，不会造成声道错位，只给出警告
+	checkAlignment := func(buf *audio.IntBuffer, name string) {
+		err := alignFrames(buf, *alignMode)
+		if err == nil {
+			return
+		}
+		if *flattenMono {
+			log.Printf("警告: %s: %v，下混为单声道时丢弃末尾不完整的帧", name, err)
+			return
+		}
+		fatalf(exitInput, "%s: %v（可用 -align-frames pad 补齐）", name, err)
+	}
+
+	for _, in := range inputs {
+		infile := in.path
+		buf, err := decodeClip(ctx, in)
+		var truncErr *truncatedDataError
+		if errors.As(err, &truncErr) && *allowTruncated {
+			log.Printf("警告: %v，只使用已有的部分", err)
+			err = nil
+		}
+		if err != nil {
+			code := exitInput
+			var ffErr *ffmpegDecodeError
+			if errors.As(err, &ffErr) {
+				code = ffmpegExitCode(err)
+			}
+			fatalf(code, "解码 %s 失败: %v", infile, err)
+		}
+		// 存在 .meta 时以其中的采样率/声道数为准
+		meta, err := loadSidecarMeta(infile)
 		if err != nil {
-			log.Fatalf("解码 %s 失败: %v", infile, err)
+			fatalf(exitInput, "读取 %s 的 sidecar 失败: %v", infile, err)
+		}
+		if meta != nil {
+			meta.apply(buf)
+		}
+		var extra map[string]json.RawMessage
+		if *mergeSidecar && in.archive == "" {
+			extra, err = loadSidecarJSON(infile)
+			if err != nil {
+				fatalf(exitInput, "读取 %s 的 sidecar 失败: %v", infile, err)
+			}
+		}
+		if err := validateFormat(buf); err != nil {
+			fatalf(exitInput, "%s: %v（可用 .meta 纠正）", infile, err)
+		}
+		checkAlignment(buf, infile)
+		if *checkLayout && looksPlanar(buf) {
+			log.Printf("警告: %s 的左右声道像是同一信号的相邻采样，文件可能按声道平铺存储，解码后声道会错乱", infile)
+		}
+		if *checkClipping {
+			if pct := clippedPercent(buf); pct > clippingWarnPercent {
+				log.Printf("警告: %s 有 %.2f%% 的采样接近满幅，可能已削波", infile, pct)
+			}
 		}
 		if outBuf == nil {
-			targetRate = buf.Format.SampleRate
+			if targetRate == 0 {
+				targetRate = buf.Format.SampleRate
+			}
+			if targetBits == 0 {
+				targetBits = buf.SourceBitDepth
+			}
+			outChannels := buf.Format.NumChannels
+			if *flattenMono {
+				outChannels = 1
+			} else if (outChannels > 2 && *downmixSurround) || *forceStereo {
+				outChannels = 2
+			}
 			outBuf = &audio.IntBuffer{
-				Format:         buf.Format,
+				Format: &audio.Format{
+					NumChannels: outChannels,
+					SampleRate:  targetRate,
+				},
 				Data:           []int{},
-				SourceBitDepth: buf.SourceBitDepth,
+				SourceBitDepth: targetBits,
 			}
+		}
+		if buf.Format.SampleRate != targetRate && *strictRate {
+			fatalf(exitInput, "%s 的采样率为 %d Hz，与目标 %d Hz 不一致（-strict-rate）", infile, buf.Format.SampleRate, targetRate)
+		}
+		if buf.Format.SampleRate != targetRate && *noResample {
+			log.Printf("警告: %s 的采样率为 %d Hz，-no-resample 下按 %d Hz 原样拼接，播放时音调和时长都会改变", infile, buf.Format.SampleRate, targetRate)
 		} else if buf.Format.SampleRate != targetRate {
-			tmpResampled, err := ffmpegResample(infile, targetRate)
+			// 源文件头不可信或片段来自压缩包时，先把解码结果写成临时 WAV 再交给 ffmpeg
+			src := infile
+			if meta != nil || in.archive != "" {
+				tmp, err := ioutil.TempFile("", "audiosprite-*.wav")
+				if err != nil {
+					fatalf(exitIO, "创建临时文件失败: %v", err)
+				}
+				tmp.Close()
+				src = tmp.Name()
+				addTemp(src)
+				writeWAV(src, buf, buf.Format.SampleRate)
+			}
+			tmpResampled, err := ffmpegResample(ctx, src, targetRate, *resampleQuality)
 			if err != nil {
-				log.Fatalf("重采样 %s 失败: %v", infile, err)
+				fatalf(ffmpegExitCode(err), "重采样 %s 失败: %v", infile, err)
+			}
+			if src != infile {
+				removeTemp(src)
 			}
-			defer os.Remove(tmpResampled)
 			buf, err = decodeWAV(tmpResampled)
 			if err != nil {
-				log.Fatalf("解码重采样文件 %s 失败: %v", tmpResampled, err)
+				fatalf(exitInput, "解码重采样文件 %s 失败: %v", tmpResampled, err)
 			}
+			checkAlignment(buf, tmpResampled)
 		}
 
-		start := float64(currentSample) / float64(targetRate)
-		outBuf.Data = append(outBuf.Data, buf.Data...)
-		currentSample += len(buf.Data) / buf.Format.NumChannels
-		end := float64(currentSample) / float64(targetRate)
+		// 环绕声输入直接拼接会打乱声道，需显式要求下混
+		if buf.Format.NumChannels > 2 {
+			if *downmixSurround {
+				// 扩展格式的声道掩码决定各声道的下混系数，没有时按默认顺序
+				mask, err := clipChannelMask(in)
+				if err != nil {
+					fatalf(exitInput, "读取 %s 的声道掩码失败: %v", infile, err)
+				}
+				if err := surroundDownmix(buf, outBuf.Format.NumChannels, mask); err != nil {
+					fatalf(exitInput, "%s: %v", infile, err)
+				}
+			} else if !*flattenMono {
+				fatalf(exitInput, "%s 是 %d 声道输入，请使用 -downmix-surround 下混", infile, buf.Format.NumChannels)
+			}
+		}
+		if *flattenMono {
+			downmixToMono(buf)
+		}
+		if *forceStereo {
+			monoToStereo(buf)
+		}
+		// 声道数不同的采样直接拼接会打乱交错顺序，之后所有片段的时间都会错位
+		if buf.Format.NumChannels != outBuf.Format.NumChannels {
+			fatalf(exitInput, "%s 是 %d 声道输入，与输出的 %d 声道不一致，请使用 -channels 1 或 -channels 2 统一声道数", infile, buf.Format.NumChannels, outBuf.Format.NumChannels)
+		}
 
-		key := fileKey(infile)
-		spritemap[key] = SpriteMapEntry{
-			Start: start,
-			End:   end,
-			Loop:  loops[filepath.Base(infile)],
+		// 统一换算到输出位深，避免不同位深的采样值直接拼接
+		convertBitDepth(buf, targetBits)
+
+		// 按 @in-out 或清单中的 in/out 截取源文件的一段
+		if in.trim != nil {
+			ch := buf.Format.NumChannels
+			from, to, err := in.trim.frames(targetRate, len(buf.Data)/ch)
+			if err != nil {
+				fatalf(exitInput, "%s: %v", infile, err)
+			}
+			buf.Data = buf.Data[from*ch : to*ch]
+		}
+
+		key := in.clipKey()
+		if *removeDC {
+			for c, dc := range removeDCOffset(buf) {
+				// 低于静音阈值的偏移只是波形本身均值的正常波动，照常去除但不提示
+				if math.Abs(dc) > dbToLinear(silenceDBFS) {
+					log.Printf("警告: %s 声道 %d 存在直流偏移 %.4f（%.1f dBFS），已去除", infile, c+1, dc, 20*math.Log10(math.Abs(dc)))
+				}
+			}
+		}
+		applyClipGain(buf, key, *preGain, gains, ducks)
+		// 清单中的 fadein/fadeout 覆盖全局设置
+		fin, fout := *fadeIn, *fadeOut
+		if in.fadeIn != nil {
+			fin = *in.fadeIn
+		}
+		if in.fadeOut != nil {
+			fout = *in.fadeOut
+		}
+		if fin > 0 || fout > 0 {
+			duration := float64(len(buf.Data)/buf.Format.NumChannels) / float64(targetRate)
+			if fin+fout > duration {
+				fatalf(exitInput, "%s: 淡入 %gs 加淡出 %gs 超过片段时长 %gs", infile, fin, fout, duration)
+			}
+			applyFade(buf, int(math.Round(fin*float64(targetRate))), int(math.Round(fout*float64(targetRate))))
+		}
+
+		loop := loops[filepath.Base(in.name())]
+		if in.hasLoop {
+			loop = in.loop
+		}
+		if in.loopRegion != nil {
+			duration := float64(len(buf.Data)/buf.Format.NumChannels) / float64(targetRate)
+			if err := in.loopRegion.validate(duration); err != nil {
+				fatalf(exitInput, "%s: %v", infile, err)
+			}
+		}
+		// -fixed-length 的静音补在片段末尾（倒放片段也是先倒放再补），计入片段区间
+		ch := buf.Format.NumChannels
+		fixed := 0
+		if *fixedLength > 0 {
+			fixed = int(math.Round(*fixedLength * float64(targetRate)))
+			if frames := len(buf.Data) / ch; frames > fixed {
+				fatalf(exitInput, "%s 时长 %gs 超过 -fixed-length %gs", infile, float64(frames)/float64(targetRate), *fixedLength)
+			}
+		}
+		fwd := sprite{key: key, loop: loop, loopRegion: in.loopRegion, source: in.source(*sourceRoot), format: in.format, meta: extra}
+		appendSprite(fwd, padFixedLength(&fwd, buf.Data, fixed, ch, buf.SourceBitDepth))
+		if *reverseSuffix != "" {
+			// 倒放片段的循环区间与正向不对应，只保留整体循环标记
+			rev := sprite{key: key + *reverseSuffix, loop: loop, source: in.source(*sourceRoot), format: in.format, meta: extra}
+			appendSprite(rev, padFixedLength(&rev, reverseFrames(buf.Data, ch), fixed, ch, buf.SourceBitDepth))
 		}
 	}
 
-	// 临时 WAV 输出
-	tmpWav := *outBase + ".wav"
-	writeWAV(tmpWav, outBuf, targetRate)
+	if *verifyGapless {
+		if err := verifyConcat(ledger, outBuf, sprites); err != nil {
+			fatalf(exitMismatch, "-verify-gapless: 拼接帧数核对失败:\n%v", err)
+		}
+	}
 
-	// 如果目标格式不是 wav，则转换
-	outAudio := *outBase + "." + strings.ToLower(*formatFlag)
-	if strings.ToLower(*formatFlag) != "wav" {
-		if err := ffmpegConvert(tmpWav, outAudio, *formatFlag); err != nil {
-			log.Fatalf("转换 %s 失败: %v", outAudio, err)
+	if *dedup && dedupCount > 0 {
+		infof("去重: %d 个片段复用了已有区间，节省 %d 字节", dedupCount, dedupSaved)
+	}
+
+	if *splitSilence {
+		threshold := *splitThreshold
+		if threshold <= 0 {
+			threshold = dbfsToAmplitude(*trimThreshold, outBuf.SourceBitDepth)
 		}
-		os.Remove(tmpWav)
-	} else {
-		outAudio = tmpWav
+		minSilence := int(math.Ceil(*splitMinSilence * float64(targetRate)))
+		sprites, err = segmentSprites(detectSegments(outBuf, threshold, minSilence), sprites, *nameTemplate, targetRate)
+		if err != nil {
+			fatalf(exitInput, "%v", err)
+		}
+		if len(sprites) == 0 {
+			fatalf(exitInput, "按静音切分后没有任何有声片段")
+		}
+	}
+
+	sprites, err = resolveSpriteCollisions(sprites, *onCollision)
+	if err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+
+	if *trimEnd {
+		trimTrailingSilence(outBuf, sprites, *trimThreshold)
+	}
+	// 补到下一个整秒的静音不属于任何片段
+	if *padToSecond {
+		ch := outBuf.Format.NumChannels
+		if rem := len(outBuf.Data) / ch % targetRate; rem != 0 {
+			outBuf.Data = append(outBuf.Data, silenceFrames(targetRate-rem, ch, outBuf.SourceBitDepth)...)
+		}
+	}
+
+	if *previewKey != "" {
+		sp, ok := findSprite(sprites, *previewKey)
+		if !ok {
+			fatalf(exitInput, "-preview: 没有键为 %s 的片段", *previewKey)
+		}
+		infof("试听 %s", *previewKey)
+		if err := previewSprite(ctx, outBuf, sp); err != nil {
+			code := exitFFmpeg
+			if errors.Is(err, context.Canceled) {
+				code = exitInterrupted
+			}
+			fatalf(code, "-preview: %v", err)
+		}
+	}
+
+	// 按 -max-file-size 拆分输出文件
+	ch := outBuf.Format.NumChannels
+	totalFrames := len(outBuf.Data) / ch
+	parts := []atlasPart{{start: 0, end: totalFrames, format: format}}
+	if grouped {
+		parts = formatGroups(sprites, totalFrames)
+	}
+	if sizeLimit > 0 {
+		var split []atlasPart
+		for _, group := range parts {
+			split = append(split, splitParts(sprites, group, sizeLimit, func(frames int) int64 {
+				return estimateSize(frames, targetRate, ch, outBuf.SourceBitDepth, group.format)
+			})...)
+		}
+		parts = split
+	}
+	assignResources(sprites, parts)
+	if len(parts) > 1 && len(formats) > 1 {
+		fatalf(exitInput, "输出被拆分为 %d 个文件，-format 只能指定一种格式", len(parts))
+	}
+	if len(parts) > 1 {
+		for _, name := range extraFormats {
+			if singleResourceExports[name] {
+				fatalf(exitInput, "输出被拆分为 %d 个文件，-export %s 只支持单个音频", len(parts), name)
+			}
+		}
+	}
+
+	var resources []string
+	convOpts := convertOptions{metadata: make(map[string]string)}
+	if oggQualitySet {
+		convOpts.oggQuality = oggQuality
+	}
+	for k, v := range map[string]string{"title": *title, "artist": *author, "comment": *comment} {
+		if v != "" {
+			convOpts.metadata[k] = v
+		}
+	}
+	// optsFor 返回 f 格式的编码参数，-format-loudness 按格式分别生效
+	optsFor := func(f string) convertOptions {
+		opts := convOpts
+		opts.volumeDB = loudness[f]
+		return opts
+	}
+	if len(formats) == 1 && format == "wav" && !grouped && len(convOpts.metadata) > 0 {
+		log.Printf("警告: wav 输出不支持标签，已忽略 -title/-author/-comment")
+	}
+	// audioPath 返回 base 的 f 格式音频路径，-format-subdirs 时位于 <输出目录>/<f>/ 下
+	audioPath := func(base, f string) string {
+		if !*formatSubdirs {
+			return base + "." + f
+		}
+		path := filepath.Join(filepath.Dir(base), f, filepath.Base(base)+"."+f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			fatalf(exitIO, "创建目录失败: %v", err)
+		}
+		return path
+	}
+
+	// 先写出全部中间 WAV，再并行转换；中间 WAV 在所有转换结束后才删除
+	var convs []conversion
+	var temps []string
+	for i, part := range parts {
+		base := *outBase
+		if len(parts) > 1 {
+			base = fmt.Sprintf("%s_%d", *outBase, i)
+		}
+		partBuf := frameSlice(outBuf, part.start, part.end)
+		partFormats := []string{part.format}
+		if len(formats) > 1 {
+			partFormats = formats
+		}
+		converting := !(len(partFormats) == 1 && partFormats[0] == "wav")
+
+		for _, f := range partFormats {
+			outAudio := audioPath(base, f)
+			resources = append(resources, outAudio)
+			addOutput(outAudio)
+		}
+		// 交付的 WAV 保持 -bits；交给 ffmpeg 的 WAV 按 -intermediate-bits 换算位深，
+		// 位深相同时直接使用交付的 WAV
+		encBuf := partBuf
+		if converting && *intermediateBits != 0 && *intermediateBits != partBuf.SourceBitDepth {
+			encBuf = &audio.IntBuffer{
+				Format:         partBuf.Format,
+				Data:           append([]int(nil), partBuf.Data...),
+				SourceBitDepth: partBuf.SourceBitDepth,
+			}
+			convertBitDepth(encBuf, *intermediateBits)
+		}
+		// 文件头的修改只用于交付的 WAV，交给 ffmpeg 的临时文件保持标准格式
+		writeDelivered := func(path string, buf *audio.IntBuffer) {
+			writeWAV(path, buf, targetRate)
+			if !wavHeader.empty() {
+				if err := fixWAVHeader(path, part.end-part.start, wavHeader); err != nil {
+					fatalf(exitIO, "改写 %s 的文件头失败: %v", path, err)
+				}
+			}
+		}
+		var ffmpegWav string
+		if !converting || hasFormat(partFormats, "wav") {
+			ffmpegWav = audioPath(base, "wav")
+			writeDelivered(ffmpegWav, partBuf)
+		}
+		switch {
+		case !converting || (ffmpegWav != "" && encBuf == partBuf):
+		case ffmpegWav == "" && *keepWAV:
+			// 拆分为多个文件时保留的 WAV 不加入 resources，以免打乱片段的 resource 下标
+			ffmpegWav = audioPath(base, "wav")
+			addOutput(ffmpegWav)
+			if len(parts) == 1 {
+				resources = append(resources, ffmpegWav)
+			}
+			writeDelivered(ffmpegWav, encBuf)
+		default:
+			// 不保留的中间 WAV 使用唯一文件名，两个共用 -o 的并行任务不会互相覆盖
+			f, err := ioutil.TempFile(filepath.Dir(base), filepath.Base(base)+"-*.wav")
+			if err != nil {
+				fatalf(exitIO, "创建临时文件失败: %v", err)
+			}
+			f.Close()
+			ffmpegWav = f.Name()
+			addTemp(ffmpegWav)
+			temps = append(temps, ffmpegWav)
+			writeWAV(ffmpegWav, encBuf, targetRate)
+		}
+
+		// 如果目标格式不是 wav，则转换
+		for _, f := range partFormats {
+			if f != "wav" {
+				convs = append(convs, conversion{input: ffmpegWav, output: audioPath(base, f), format: f})
+			}
+		}
+	}
+	if err := runConversions(ctx, convs, *jobs, optsFor); err != nil {
+		fatalf(ffmpegExitCode(err), "%v", err)
+	}
+	for _, tmp := range temps {
+		removeTemp(tmp)
+	}
+
+	if *individualDir != "" {
+		if err := writeIndividualFiles(ctx, *individualDir, outBuf, sprites, targetRate, format, optsFor); err != nil {
+			code := ffmpegExitCode(err)
+			if _, ok := err.(*os.PathError); ok {
+				code = exitIO
+			}
+			fatalf(code, "生成单独的片段文件失败: %v", err)
+		}
+	}
+
+	// ffmpeg 个别情况下返回 0 却没有输出，写 JSON 前确认每个资源都已生成
+	if err := verifyResources(resources); err != nil {
+		fatalf(exitFFmpeg, "%v", err)
 	}
 
 	// 写出 JSON
-	sprite := SpriteJSON{
-		Resources: []string{outAudio},
-		Spritemap: spritemap,
+	spritemap := buildSpritemap(sprites, parts, targetRate, *roundMode)
+	if *noLoopInExport {
+		stripLoops(spritemap)
+	}
+	if err := checkFinite(spritemap, sprites); err != nil {
+		fatalf(exitInput, "%v", err)
 	}
-	data, _ := json.MarshalIndent(sprite, "", "  ")
-	if err := ioutil.WriteFile(*outBase+".json", data, 0644); err != nil {
-		log.Fatalf("写入 JSON 失败: %v", err)
+	if *verifyGapless {
+		if err := verifySpritemapFrames(spritemap, sprites, parts, targetRate); err != nil {
+			fatalf(exitMismatch, "-verify-gapless: 清单时间核对失败:\n%v", err)
+		}
+	}
+	if !*noJSON {
+		// -embed 只替换写进清单的 resources，索引、模板等仍指向音频文件
+		jsonResources := resources
+		// -format-subdirs 时清单与各格式目录一起部署，resources 写为相对清单的路径
+		if *formatSubdirs {
+			jsonResources = relativePaths(filepath.Dir(*outBase), resources)
+		}
+		if *embed {
+			uris, size, err := dataURIs(resources)
+			if err != nil {
+				fatalf(exitIO, "读取音频以内嵌失败: %v", err)
+			}
+			if size > embedWarnSize {
+				log.Printf("警告: 内嵌的音频共 %.1f MB，JSON 会很大，加载和解析都会变慢", float64(size)/(1<<20))
+			}
+			jsonResources = uris
+		}
+		sprite := SpriteJSON{
+			Resources: jsonResources,
+			Spritemap: spritemap,
+		}
+		if exportMode == "both" {
+			addMilliseconds(sprite.Spritemap)
+		}
+		if *timeBase > 0 {
+			sprite.TimeBase = *timeBase
+			addTimeUnits(sprite.Spritemap, *timeBase)
+		}
+		if *includeSources {
+			addSources(sprite.Spritemap, sprites)
+		}
+		if *mergeSidecar {
+			addMeta(sprite.Spritemap, sprites)
+		}
+		if *stats {
+			addDurationPct(sprite.Spritemap, float64(totalFrames)/float64(targetRate))
+		}
+		wrap := func(sprite SpriteJSON) interface{} {
+			var manifest interface{} = sprite
+			if *numbersAsStrings {
+				manifest = withStringTimes(sprite)
+			}
+			if *jsonRootKey != "" {
+				manifest = map[string]interface{}{*jsonRootKey: manifest}
+			}
+			return manifest
+		}
+		data, _ := marshalManifest(wrap(sprite), *jsonStyle)
+		// spriteId 覆盖最终的 PCM、不含 spriteId 的清单以及只在编码时生效的参数
+		if *withSpriteID {
+			sprite.SpriteID = spriteID(spriteParams{
+				Inputs:           inputIDs(inputs),
+				Rate:             targetRate,
+				Bits:             outBuf.SourceBitDepth,
+				Channels:         outBuf.Format.NumChannels,
+				Audio:            pcmDigest(outBuf),
+				Manifest:         manifestDigest(data),
+				Formats:          formats,
+				IntermediateBits: *intermediateBits,
+				OggQuality:       convOpts.oggQuality,
+				Loudness:         loudness,
+				Metadata:         convOpts.metadata,
+				WAVFact:          wavHeader.fact,
+				WAVBlockAlign:    wavHeader.blockAlign,
+				WAVByteRate:      wavHeader.byteRate,
+			})
+			data, _ = marshalManifest(wrap(sprite), *jsonStyle)
+		}
+		addOutput(*outBase + ".json")
+		if err := ioutil.WriteFile(*outBase+".json", data, 0644); err != nil {
+			fatalf(exitIO, "写入 JSON 失败: %v", err)
+		}
+		for _, name := range extraFormats {
+			out := *outBase + "." + name + ".json"
+			data, _ := marshalManifest(extraExports[name](spritemap, jsonResources), *jsonStyle)
+			addOutput(out)
+			if err := ioutil.WriteFile(out, data, 0644); err != nil {
+				fatalf(exitIO, "写入 %s 失败: %v", out, err)
+			}
+		}
+	}
+
+	// 索引可能已包含其他构建的条目，失败时不删除
+	if *indexFile != "" {
+		// 索引下次构建还要读回合并，escaped 时按 compact 写出
+		style := *jsonStyle
+		if style == jsonStyleEscaped {
+			style = jsonStyleCompact
+		}
+		if err := updateIndex(*indexFile, *outBase+".json", spritemap, resources, style); err != nil {
+			fatalf(exitIO, "更新索引 %s 失败: %v", *indexFile, err)
+		}
+	}
+
+	if *templateFile != "" {
+		out := *templateOut
+		if out == "" {
+			out = templateOutputPath(*outBase, *templateFile)
+		}
+		addOutput(out)
+		if err := writeTemplate(*templateFile, out, sprites, spritemap, resources); err != nil {
+			fatalf(exitIO, "渲染模板 %s 失败: %v", *templateFile, err)
+		}
 	}
 
-	fmt.Printf("生成 %s 和 %s 完成\n", outAudio, *outBase+".json")
+	if *waveformOut != "" {
+		addOutput(*waveformOut)
+		if err := writeWaveformPNG(*waveformOut, outBuf, sprites); err != nil {
+			fatalf(exitIO, "写入波形图失败: %v", err)
+		}
+	}
+
+	if *reportOut != "" {
+		var waveBuf *audio.IntBuffer
+		if *waveformOut != "" {
+			waveBuf = outBuf
+		}
+		reportTitle := *title
+		if reportTitle == "" {
+			reportTitle = filepath.Base(*outBase)
+		}
+		addOutput(*reportOut)
+		if err := writeReport(*reportOut, reportTitle, sprites, spritemap, resources, waveBuf); err != nil {
+			fatalf(exitIO, "写入报告失败: %v", err)
+		}
+	}
+
+	if *playlist != "" {
+		addOutput(*playlist)
+		if err := writeCueSheet(*playlist, sprites, parts, resources, targetRate); err != nil {
+			fatalf(exitIO, "写入播放列表失败: %v", err)
+		}
+	}
+
+	if err := flushCommandLog(); err != nil {
+		fatalf(exitIO, "写入命令日志失败: %v", err)
+	}
+
+	finishCleanup()
+	if err := stopProfiling(); err != nil {
+		fatalf(exitIO, "写入 profile 失败: %v", err)
+	}
+
+	// stdout 留给 -print 的输出时，完成提示改写到 stderr
+	msgOut := io.Writer(os.Stdout)
+	if *printMode == "summary" {
+		if err := writeSummary(os.Stdout, sprites, parts, targetRate); err != nil {
+			fatalf(exitIO, "输出摘要失败: %v", err)
+		}
+		msgOut = os.Stderr
+	}
+
+	if *noJSON {
+		donef(msgOut, "生成 %s 完成\n", strings.Join(resources, ", "))
+	} else {
+		donef(msgOut, "生成 %s 和 %s 完成\n", strings.Join(resources, ", "), *outBase+".json")
+	}
+}
+
+// relativePaths 把 paths 改写为相对 dir 的斜杠路径，无法改写的保持原样
+func relativePaths(dir string, paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		if r, err := filepath.Rel(dir, p); err == nil {
+			p = r
+		}
+		out[i] = filepath.ToSlash(p)
+	}
+	return out
+}
+
+// checkWritable 在 dir 中创建并删除一个临时文件，以确认目录可写
+func checkWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".audiosprite-probe-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// decodeClip 解码一个输入片段，压缩包条目直接在内存中解码；
+// ADPCM 等 go-audio 不支持的编码回退到 ffmpeg
+func decodeClip(ctx context.Context, in clipSpec) (*audio.IntBuffer, error) {
+	var buf *audio.IntBuffer
+	var err error
+	if in.archive != "" {
+		buf, err = decodeArchiveEntry(in.archive, in.entry)
+	} else {
+		buf, err = decodeWAV(in.path)
+	}
+	var codecErr *unsupportedCodecError
+	if errors.As(err, &codecErr) {
+		return ffmpegDecodeClip(ctx, in)
+	}
+	return buf, err
+}
+
+// findInput 按路径或文件名查找输入片段
+func findInput(inputs []clipSpec, name string) (clipSpec, bool) {
+	for _, in := range inputs {
+		if in.path == name || in.name() == name || filepath.Base(in.name()) == name {
+			return in, true
+		}
+	}
+	return clipSpec{}, false
+}
+
+// inputSampleRate 返回输入片段的采样率，存在 .meta 时以其为准
+func inputSampleRate(ctx context.Context, in clipSpec) (int, error) {
+	// 文件被截断不影响文件头中的采样率，是否允许由解码时的检查决定
+	buf, err := decodeClip(ctx, in)
+	var truncErr *truncatedDataError
+	if err != nil && !errors.As(err, &truncErr) {
+		return 0, err
+	}
+	meta, err := loadSidecarMeta(in.path)
+	if err != nil {
+		return 0, err
+	}
+	if meta != nil {
+		meta.apply(buf)
+	}
+	if buf.Format.SampleRate <= 0 {
+		return 0, fmt.Errorf("文件头中的采样率 %d 无效", buf.Format.SampleRate)
+	}
+	return buf.Format.SampleRate, nil
+}
+
+// validateFormat 检查解码结果的采样率和声道数；为 0 会让后面的时间换算得到 Inf/NaN，
+// 需要尽早报错
+func validateFormat(buf *audio.IntBuffer) error {
+	if buf.Format.SampleRate <= 0 || buf.Format.NumChannels <= 0 {
+		return fmt.Errorf("文件头无效: 采样率 %d，声道数 %d", buf.Format.SampleRate, buf.Format.NumChannels)
+	}
+	return nil
 }
 
 func decodeWAV(path string) (*audio.IntBuffer, error) {
@@ -137,52 +1223,237 @@ func decodeWAV(path string) (*audio.IntBuffer, error) {
 		return nil, err
 	}
 	defer f.Close()
-	dec := wav.NewDecoder(f)
+	return decodeWAVReader(f, path)
+}
+
+// decodeWAVReader 从 r 解码 WAV，path 仅用于错误信息。
+// 采样数与 data 块声明的长度不符时同时返回已解码的缓冲和 *truncatedDataError
+func decodeWAVReader(r io.ReadSeeker, path string) (*audio.IntBuffer, error) {
+	fmtChunk, err := readWAVFmt(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if tag := fmtChunk.tag; tag != 0 && tag != wavFormatPCM && tag != wavFormatFloat {
+		return nil, &unsupportedCodecError{tag}
+	}
+	dec := wav.NewDecoder(r)
 	if !dec.IsValidFile() {
 		return nil, fmt.Errorf("%s 不是有效 WAV", path)
 	}
-	return dec.FullPCMBuffer()
+	buf, err := dec.FullPCMBuffer()
+	if err != nil {
+		return nil, err
+	}
+	var truncErr error
+	if bytesPerSample := int(dec.BitDepth) / 8; bytesPerSample > 0 {
+		if declared := dec.PCMSize / bytesPerSample; declared != len(buf.Data) {
+			truncErr = &truncatedDataError{path: path, declared: declared, decoded: len(buf.Data)}
+		}
+	}
+	if fmtChunk.tag == wavFormatFloat {
+		if err := floatToInt(buf, floatTargetBits); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return buf, truncErr
 }
 
 func writeWAV(path string, buf *audio.IntBuffer, sampleRate int) {
 	f, err := os.Create(path)
 	if err != nil {
-		log.Fatalf("创建输出文件失败: %v", err)
+		fatalf(exitIO, "创建输出文件失败: %v", err)
 	}
 	defer f.Close()
 	enc := wav.NewEncoder(f, sampleRate, buf.SourceBitDepth, buf.Format.NumChannels, 1)
 	if err := enc.Write(buf); err != nil {
-		log.Fatalf("写入 WAV 失败: %v", err)
+		fatalf(exitIO, "写入 WAV 失败: %v", err)
 	}
 	enc.Close()
 }
 
-func ffmpegResample(input string, rate int) (string, error) {
-	tmp := fmt.Sprintf("%s_resampled_%d.wav", input, rate)
-	cmd := exec.Command("ffmpeg", "-y", "-i", input,
-		"-ar", fmt.Sprint(rate), tmp)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("ffmpeg error: %v, %s", err, string(out))
+// resampleFilters 是 -resample-quality 各档对应的 ffmpeg 滤镜，default 使用 ffmpeg 自带的重采样器
+var resampleFilters = map[string]string{
+	"default":  "",
+	"high":     "aresample=resampler=soxr:precision=20",
+	"veryhigh": "aresample=resampler=soxr:precision=28",
+}
+
+// ffmpegResample 调用 ffmpeg 把 input 重采样到 rate，返回临时文件路径；
+// quality 为 resampleFilters 的键。
+// ctx 取消时 ffmpeg 子进程会被终止，未完成的临时文件随之删除。
+func ffmpegResample(ctx context.Context, input string, rate int, quality string) (string, error) {
+	// 临时文件名带随机部分，避免同一目录下并行运行时互相覆盖
+	f, err := ioutil.TempFile("", fmt.Sprintf("audiosprite-resampled-%d-*.wav", rate))
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	tmp := f.Name()
+	addTemp(tmp)
+	args := []string{"-y", "-i", input}
+	if filter := resampleFilters[quality]; filter != "" {
+		args = append(args, "-af", filter)
+	}
+	args = append(args, "-ar", fmt.Sprint(rate), tmp)
+	out, err := runFFmpeg(ctx, args...)
+	if err != nil {
+		removeTemp(tmp)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("ffmpeg error: %w, %s", err, string(out))
 	}
 	return tmp, nil
 }
 
-func ffmpegConvert(input, output, format string) error {
-	args := []string{"-y", "-i", input}
+// convertOptions 是 ffmpegConvert 的可选编码参数
+type convertOptions struct {
+	// metadata 写入 ID3 / Vorbis comment 的标签，如 title、artist、comment
+	metadata map[string]string
+	// oggQuality 非 nil 时作为 libvorbis 的 -qscale:a
+	oggQuality *float64
+	// volumeDB 非 0 时在编码前按该分贝数调整音量，抵消编码器带来的响度偏差
+	volumeDB float64
+}
+
+// ffmpegConvert 调用 ffmpeg 把 input 转换为 format 格式的 output，
+// 失败或 ctx 取消时删除写了一半的 output
+func ffmpegConvert(ctx context.Context, input, output, format string, opts convertOptions) error {
+	args := append([]string{"-y", "-i", input}, encoderArgs(format, opts)...)
+	args = append(args, output)
+	if out, err := runFFmpeg(ctx, args...); err != nil {
+		os.Remove(output)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ffmpeg convert error: %w, %s", err, string(out))
+	}
+	return nil
+}
+
+// buildSpritemap 将帧区间换算为以秒为单位的 spritemap，
+// 时间相对片段所在的输出文件 parts[sp.resource]，round 见 frameSeconds
+func buildSpritemap(sprites []sprite, parts []atlasPart, rate int, round string) map[string]SpriteMapEntry {
+	spritemap := make(map[string]SpriteMapEntry, len(sprites))
+	for _, sp := range sprites {
+		offset := parts[sp.resource].start
+		loop := sp.loop
+		entry := SpriteMapEntry{
+			Start:    frameSeconds(sp.start-offset, rate, round),
+			End:      frameSeconds(sp.end-offset, rate, round),
+			Loop:     &loop,
+			Resource: sp.resource,
+		}
+		if sp.loopRegion != nil {
+			loopStart, loopEnd := sp.loopRegion.start, sp.loopRegion.end
+			entry.LoopStart = &loopStart
+			entry.LoopEnd = &loopEnd
+		}
+		spritemap[sp.key] = entry
+	}
+	return spritemap
+}
+
+// -json-style 的取值
+const (
+	jsonStylePretty  = "pretty"
+	jsonStyleCompact = "compact"
+	// jsonStyleEscaped 把紧凑 JSON 再编码为一个 JSON 字符串，
+	// 它同时是合法的 Go 与 JS 双引号字符串字面量，可直接粘贴进源码
+	jsonStyleEscaped = "escaped"
+)
+
+// resolveJSONStyle 确定 JSON 输出风格：指定了 -json-style 时以其为准，
+// 否则按 -json-pretty 在 pretty 与 compact 之间选择。prettySet 表示显式给出了
+// -json-pretty，此时它与 -json-style 矛盾（如 pretty 配 -json-pretty=false，
+// 或单行的 compact/escaped 配 -json-pretty=true）时返回错误
+func resolveJSONStyle(style string, pretty, prettySet bool) (string, error) {
+	switch style {
+	case "":
+		if !pretty {
+			return jsonStyleCompact, nil
+		}
+		return jsonStylePretty, nil
+	case jsonStylePretty, jsonStyleCompact, jsonStyleEscaped:
+		if prettySet && pretty != (style == jsonStylePretty) {
+			return "", fmt.Errorf("-json-style %s 与 -json-pretty=%t 矛盾，请只指定其中一个", style, pretty)
+		}
+		return style, nil
+	}
+	return "", fmt.Errorf("不支持的 -json-style 取值: %s，仅支持 pretty, compact, escaped", style)
+}
+
+// marshalManifest 按 -json-style 序列化清单：pretty 时两空格缩进，
+// compact 输出不含空白的单行，escaped 再把单行结果转义为字符串字面量
+func marshalManifest(v interface{}, style string) ([]byte, error) {
+	if style == jsonStylePretty {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	data, err := json.Marshal(v)
+	if err != nil || style != jsonStyleEscaped {
+		return data, err
+	}
+	// 不做 HTML 转义，保留 < > & 原样；U+2028/U+2029 仍会转义，JS 中同样安全
+	var quoted bytes.Buffer
+	enc := json.NewEncoder(&quoted)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(string(data)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(quoted.Bytes(), []byte("\n")), nil
+}
+
+// encoderArgs 返回 format 对应的编码器及标签参数
+func encoderArgs(format string, opts convertOptions) []string {
+	var args []string
 	// 自动选择编码器
 	if strings.ToLower(format) == "mp3" {
 		args = append(args, "-codec:a", "libmp3lame", "-qscale:a", "2")
 	} else if strings.ToLower(format) == "ogg" {
 		args = append(args, "-codec:a", "libvorbis")
+		if opts.oggQuality != nil {
+			args = append(args, "-qscale:a", strconv.FormatFloat(*opts.oggQuality, 'g', -1, 64))
+		}
 	}
-	args = append(args, output)
-	cmd := exec.Command("ffmpeg", args...)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("ffmpeg convert error: %v, %s", err, string(out))
+	if opts.volumeDB != 0 {
+		args = append(args, "-af", "volume="+strconv.FormatFloat(opts.volumeDB, 'g', -1, 64)+"dB")
+	}
+	keys := make([]string, 0, len(opts.metadata))
+	for k := range opts.metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-metadata", k+"="+opts.metadata[k])
+	}
+	return args
+}
+
+// verifyResources 检查 resources 中的每个文件都存在且非空
+func verifyResources(resources []string) error {
+	for _, r := range resources {
+		info, err := os.Stat(r)
+		if err != nil {
+			return fmt.Errorf("输出文件 %s 不存在: %v", r, err)
+		}
+		if info.Size() == 0 {
+			return fmt.Errorf("输出文件 %s 为空", r)
+		}
 	}
 	return nil
 }
 
+// runFFmpegOnce 以 ctx 运行一次 ffmpeg 并返回合并的 stdout/stderr 输出，
+// 运行期间登记子进程以便中断时终止
+func runFFmpegOnce(ctx context.Context, args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := runCommand(cmd)
+	return out.Bytes(), err
+}
+
 func fileKey(path string) string {
 	base := filepath.Base(path)
 	ext := filepath.Ext(base)
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testManifest 返回一个固定内容的清单，用于比较序列化结果
+func testManifest() SpriteJSON {
+	loop := true
+	return SpriteJSON{
+		Resources: []string{"sfx.mp3"},
+		Spritemap: map[string]SpriteMapEntry{
+			"b": {Start: 0.5, End: 1.25, Loop: &loop},
+			"a": {Start: 0, End: 0.5},
+		},
+	}
+}
+
+func TestMarshalManifest(t *testing.T) {
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{jsonStylePretty, `{
+  "resources": [
+    "sfx.mp3"
+  ],
+  "spritemap": {
+    "a": {
+      "start": 0,
+      "end": 0.5
+    },
+    "b": {
+      "start": 0.5,
+      "end": 1.25,
+      "loop": true
+    }
+  }
+}`},
+		{jsonStyleCompact, `{"resources":["sfx.mp3"],"spritemap":{"a":{"start":0,"end":0.5},"b":{"start":0.5,"end":1.25,"loop":true}}}`},
+	}
+	for _, tt := range tests {
+		got, err := marshalManifest(testManifest(), tt.style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s:\n%s\nwant\n%s", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestResolveJSONStyle(t *testing.T) {
+	tests := []struct {
+		style             string
+		pretty, prettySet bool
+		want              string
+		wantErr           bool
+	}{
+		{"", true, false, jsonStylePretty, false},
+		{"", true, true, jsonStylePretty, false},
+		{"", false, true, jsonStyleCompact, false},
+		// 只指定 -json-style 时 -json-pretty 的默认值不起作用
+		{jsonStyleCompact, true, false, jsonStyleCompact, false},
+		{jsonStyleEscaped, true, false, jsonStyleEscaped, false},
+		{jsonStylePretty, true, false, jsonStylePretty, false},
+		// 显式给出的两个选项一致
+		{jsonStylePretty, true, true, jsonStylePretty, false},
+		{jsonStyleCompact, false, true, jsonStyleCompact, false},
+		{jsonStyleEscaped, false, true, jsonStyleEscaped, false},
+		// 显式给出的两个选项矛盾
+		{jsonStylePretty, false, true, "", true},
+		{jsonStyleCompact, true, true, "", true},
+		{jsonStyleEscaped, true, true, "", true},
+		{"yaml", true, false, "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolveJSONStyle(tt.style, tt.pretty, tt.prettySet)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveJSONStyle(%q, %t, %t) = %q, %v; want %q", tt.style, tt.pretty, tt.prettySet, got, err, tt.want)
+		}
+	}
+}
+
+func TestZeroRateHeader(t *testing.T) {
+	// go-audio 把 0 Hz 的文件头视为无效文件，不会得到采样率为 0 的缓冲
+	wav := wavFixture{tag: wavFormatPCM, channels: 1, rate: 0, bits: 16, data: make([]byte, 8)}
+	if _, err := decodeWAVReader(bytes.NewReader(wav.bytes()), "zero.wav"); err == nil {
+		t.Fatal("0 Hz 的 WAV 应解码失败")
+	}
+
+	// 其他来源（ffmpeg 回退、.meta）得到的 0 Hz 缓冲由 validateFormat 拦下
+	buf := monoBuffer(16, 0, 0)
+	buf.Format.SampleRate = 0
+	if err := validateFormat(buf); err == nil {
+		t.Error("采样率为 0 应返回错误")
+	}
+	buf.Format.SampleRate = 44100
+	buf.Format.NumChannels = 0
+	if err := validateFormat(buf); err == nil {
+		t.Error("声道数为 0 应返回错误")
+	}
+	buf.Format.NumChannels = 1
+	if err := validateFormat(buf); err != nil {
+		t.Errorf("有效的文件头返回错误: %v", err)
+	}
+}
+
+func TestCheckFinite(t *testing.T) {
+	// 以 0 Hz 换算的时间为 NaN/Inf，写 JSON 前报出片段和源文件
+	sprites := []sprite{{key: "a", start: 10, end: 20, source: "zero.wav"}}
+	spritemap := buildSpritemap(sprites, []atlasPart{{start: 0, end: 20}}, 44100, roundRaw)
+	if err := checkFinite(spritemap, sprites); err != nil {
+		t.Fatalf("有限的时间返回错误: %v", err)
+	}
+	spritemap = buildSpritemap(sprites, []atlasPart{{start: 0, end: 20}}, 0, roundRaw)
+	err := checkFinite(spritemap, sprites)
+	if err == nil || !strings.Contains(err.Error(), "zero.wav") {
+		t.Errorf("checkFinite = %v, want 指向 zero.wav 的错误", err)
+	}
+}
+
+func TestMarshalManifestEscaped(t *testing.T) {
+	got, err := marshalManifest(testManifest(), jsonStyleEscaped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"{\"resources\":[\"sfx.mp3\"],\"spritemap\":{\"a\":{\"start\":0,\"end\":0.5},\"b\":{\"start\":0.5,\"end\":1.25,\"loop\":true}}}"`
+	if string(got) != want {
+		t.Errorf("escaped:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshalManifestEscapedSpecialCharacters(t *testing.T) {
+	// 字符串中的引号、反斜杠、< > & 与 U+2028 经过两次编码后的结果
+	v := map[string]string{"k": "a\"b\\c<d>&e "}
+	got, err := marshalManifest(v, jsonStyleEscaped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"{\"k\":\"a\\\"b\\\\c\\u003cd\\u003e\\u0026e\\u2028\"}"`
+	if string(got) != want {
+		t.Errorf("escaped:\n%s\nwant\n%s", got, want)
+	}
+	// 结果是一个 JSON 字符串，解码后得到紧凑 JSON
+	var inner string
+	if err := json.Unmarshal(got, &inner); err != nil {
+		t.Fatal(err)
+	}
+	compact, _ := marshalManifest(v, jsonStyleCompact)
+	if inner != string(compact) {
+		t.Errorf("解码后 = %s, want %s", inner, compact)
+	}
+}
+
+func TestRelativePaths(t *testing.T) {
+	got := relativePaths("dist", []string{"dist/mp3/sfx.mp3", "dist/ogg/sfx.ogg"})
+	if want := []string{"mp3/sfx.mp3", "ogg/sfx.ogg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("relativePaths = %v, want %v", got, want)
+	}
+	if got := relativePaths(".", []string{"wav/sfx.wav"}); got[0] != "wav/sfx.wav" {
+		t.Errorf("relativePaths(.) = %v", got)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// buildManifest 是 -manifest 指定的构建清单，按顺序列出输入片段及其属性
+type buildManifest struct {
+	Clips []manifestClip `json:"clips"`
+}
+
+// manifestClip 是清单中的一个片段；file 为相对清单文件所在目录的路径
+type manifestClip struct {
+	File string `json:"file"`
+	Loop *bool  `json:"loop"`
+	// LoopStart/LoopEnd 是相对片段起点的循环区间（秒），用于先播前奏再循环中段
+	LoopStart *float64 `json:"loopStart"`
+	LoopEnd   *float64 `json:"loopEnd"`
+	// In/Out 是从源文件中截取的区间（秒），Out 省略时截到结尾
+	In  *float64 `json:"in"`
+	Out *float64 `json:"out"`
+	// Format 指定片段的输出格式，不同格式的片段分到不同的音频文件
+	Format string `json:"format"`
+	// FadeIn/FadeOut 是片段首尾的淡入/淡出秒数，覆盖 -fadein/-fadeout
+	FadeIn  *float64 `json:"fadein"`
+	FadeOut *float64 `json:"fadeout"`
+}
+
+// readManifest 读取 JSON 构建清单并转换为输入列表
+func readManifest(path string) ([]clipSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m buildManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	specs := make([]clipSpec, 0, len(m.Clips))
+	for i, c := range m.Clips {
+		if c.File == "" {
+			return nil, fmt.Errorf("%s: 第 %d 个片段缺少 file", path, i+1)
+		}
+		spec := clipSpec{path: c.File}
+		if !filepath.IsAbs(spec.path) {
+			spec.path = filepath.Join(dir, spec.path)
+		}
+		if c.Loop != nil {
+			spec.hasLoop = true
+			spec.loop = *c.Loop
+		}
+		if (c.LoopStart == nil) != (c.LoopEnd == nil) {
+			return nil, fmt.Errorf("%s: %s 的 loopStart 和 loopEnd 必须同时指定", path, c.File)
+		}
+		if c.LoopStart != nil {
+			spec.loopRegion = &loopRegion{start: *c.LoopStart, end: *c.LoopEnd}
+			// 指定了循环区间但未写 loop 时默认循环
+			if !spec.hasLoop {
+				spec.hasLoop = true
+				spec.loop = true
+			}
+		}
+		if c.In != nil || c.Out != nil {
+			spec.trim = &clipRange{}
+			if c.In != nil {
+				spec.trim.in = *c.In
+			}
+			if c.Out != nil {
+				spec.trim.out = *c.Out
+			}
+		}
+		if c.Format != "" {
+			spec.format = strings.ToLower(c.Format)
+			if spec.format != "wav" && spec.format != "mp3" && spec.format != "ogg" {
+				return nil, fmt.Errorf("%s: %s 的 format %q 不支持，仅支持 wav, mp3, ogg", path, c.File, c.Format)
+			}
+		}
+		if (c.FadeIn != nil && *c.FadeIn < 0) || (c.FadeOut != nil && *c.FadeOut < 0) {
+			return nil, fmt.Errorf("%s: %s 的 fadein/fadeout 不能为负数", path, c.File)
+		}
+		spec.fadeIn, spec.fadeOut = c.FadeIn, c.FadeOut
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
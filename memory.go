@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// memWriteSeeker 是基于内存的 io.WriteSeeker，供 wav 编码器回写文件头使用
+type memWriteSeeker struct {
+	buf []byte
+	pos int
+}
+
+func (m *memWriteSeeker) Write(p []byte) (int, error) {
+	if end := m.pos + len(p); end > len(m.buf) {
+		m.buf = append(m.buf, make([]byte, end-len(m.buf))...)
+	}
+	n := copy(m.buf[m.pos:], p)
+	m.pos += n
+	return n, nil
+}
+
+func (m *memWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = int64(m.pos) + offset
+	case io.SeekEnd:
+		abs = int64(len(m.buf)) + offset
+	default:
+		return 0, errors.New("memWriteSeeker: 无效的 whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("memWriteSeeker: 偏移为负")
+	}
+	m.pos = int(abs)
+	return abs, nil
+}
+
+// encodeWAVBytes 在内存中把 buf 编码为完整的 WAV 文件内容
+func encodeWAVBytes(buf *audio.IntBuffer, sampleRate int) ([]byte, error) {
+	w := &memWriteSeeker{}
+	enc := wav.NewEncoder(w, sampleRate, buf.SourceBitDepth, buf.Format.NumChannels, 1)
+	if err := enc.Write(buf); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+// pipeFormats 是 ffmpeg 可以直接写到 stdout 的输出容器格式
+var pipeFormats = map[string]string{"mp3": "mp3", "ogg": "ogg"}
+
+// encodeAudioBytes 不经过文件系统把 buf 编码为 format 格式：
+// wav 直接在内存中编码，mp3/ogg 通过管道交给 ffmpeg（stdin 输入 WAV，stdout 读出结果）。
+// 需要可回写输出的容器（如 m4a）无法走管道，不在支持之列。
+func encodeAudioBytes(ctx context.Context, buf *audio.IntBuffer, sampleRate int, format string, opts convertOptions) ([]byte, error) {
+	wavData, err := encodeWAVBytes(buf, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	format = strings.ToLower(format)
+	if format == "wav" {
+		return wavData, nil
+	}
+	container, ok := pipeFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("格式 %s 无法在内存中生成", format)
+	}
+
+	args := append([]string{"-f", "wav", "-i", "pipe:0"}, encoderArgs(format, opts)...)
+	args = append(args, "-f", container, "pipe:1")
+	var stdout bytes.Buffer
+	// 与其他 ffmpeg 调用一样按 -ffmpeg-retries 重试，每次重新送入完整的 WAV
+	stderr, err := retryFFmpeg(ctx, func() ([]byte, error) {
+		stdout.Reset()
+		return runFFmpegPipe(ctx, wavData, &stdout, args...)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("ffmpeg convert error: %w, %s", err, string(stderr))
+	}
+	return stdout.Bytes(), nil
+}
+
+// runFFmpegPipe 以 ctx 运行一次 ffmpeg，stdin 送入 input，stdout 写到 w，
+// 返回 stderr 的输出；运行期间登记子进程以便中断时终止
+func runFFmpegPipe(ctx context.Context, input []byte, w io.Writer, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	err := runCommand(cmd)
+	return stderr.Bytes(), err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// 合并时键名冲突的额外处理方式：给后出现的键加上所在 JSON 的基名前缀
+const collisionPrefix = "prefix"
+
+// mergeCommand 实现 merge 子命令：把多个已有的 JSON 合并为一个多资源清单，
+// 不重新编码音频
+func mergeCommand(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("o", "merged.json", "合并后的 JSON 路径")
+	onCollision := fs.String("on-collision", collisionError, "不同 JSON 中键名相同时的处理方式: error, prefix（后出现的键改为 <JSON 基名>_<键>）, skip, replace")
+	jsonStyle := fs.String("json-style", jsonStylePretty, "JSON 输出风格: pretty, compact, escaped")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s merge [选项] a.json b.json...\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(exitInput)
+	}
+	switch *onCollision {
+	case collisionError, collisionPrefix, collisionSkip, collisionReplace:
+	default:
+		fatalf(exitInput, "不支持的 -on-collision 取值: %s，仅支持 error, prefix, skip, replace", *onCollision)
+	}
+	switch *jsonStyle {
+	case jsonStylePretty, jsonStyleCompact, jsonStyleEscaped:
+	default:
+		fatalf(exitInput, "不支持的 -json-style 取值: %s，仅支持 pretty, compact, escaped", *jsonStyle)
+	}
+
+	var manifests []SpriteJSON
+	for _, path := range fs.Args() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			fatalf(exitInput, "读取 %s 失败: %v", path, err)
+		}
+		var m SpriteJSON
+		if err := json.Unmarshal(data, &m); err != nil {
+			fatalf(exitInput, "解析 %s 失败: %v", path, err)
+		}
+		manifests = append(manifests, m)
+	}
+	merged, err := mergeManifests(fs.Args(), manifests, *onCollision)
+	if err != nil {
+		fatalf(exitInput, "%v", err)
+	}
+	data, _ := marshalManifest(merged, *jsonStyle)
+	if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		fatalf(exitIO, "写入 %s 失败: %v", *out, err)
+	}
+	donef(os.Stdout, "合并 %d 个清单到 %s 完成\n", len(manifests), *out)
+}
+
+// mergeManifests 依次拼接各清单的 resources，并把每个条目的 resource 下标加上之前清单的资源数；
+// start/end 相对所在音频文件，无需改动。names[i] 是 manifests[i] 的文件路径，用于报错和加前缀
+func mergeManifests(names []string, manifests []SpriteJSON, policy string) (SpriteJSON, error) {
+	merged := SpriteJSON{Spritemap: make(map[string]SpriteMapEntry)}
+	owner := make(map[string]string)
+	for i, m := range manifests {
+		// startUnits/endUnits 的单位不同时无法合并
+		if i == 0 {
+			merged.TimeBase = m.TimeBase
+		} else if m.TimeBase != merged.TimeBase {
+			return SpriteJSON{}, fmt.Errorf("%s 的 timeBase %d 与 %s 的 %d 不同", names[i], m.TimeBase, names[0], merged.TimeBase)
+		}
+		offset := len(merged.Resources)
+		merged.Resources = append(merged.Resources, m.Resources...)
+		for _, key := range sortedKeys(m.Spritemap) {
+			entry := m.Spritemap[key]
+			entry.Resource += offset
+			if prev, ok := owner[key]; ok {
+				switch policy {
+				case collisionError:
+					return SpriteJSON{}, fmt.Errorf("%s 与 %s 都有键 %s（可用 -on-collision prefix、skip 或 replace）", prev, names[i], key)
+				case collisionSkip:
+					continue
+				case collisionPrefix:
+					base := fileKey(names[i])
+					log.Printf("警告: 键 %s 已属于 %s，%s 中的改为 %s_%s", key, prev, names[i], base, key)
+					key = base + "_" + key
+					if _, ok := owner[key]; ok {
+						return SpriteJSON{}, fmt.Errorf("加前缀后的键 %s 仍然重复", key)
+					}
+				}
+			}
+			owner[key] = names[i]
+			merged.Spritemap[key] = entry
+		}
+	}
+	return merged, nil
+}
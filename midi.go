@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 键名来源，见 -key-from
+const (
+	keyFromName = "name"
+	keyFromMIDI = "midi"
+)
+
+// defaultMIDIKeyTemplate 是 -key-from midi 的默认键名模板
+const defaultMIDIKeyTemplate = "note_{note}"
+
+// midiKey 把文件名开头的十进制数（可带前导零，如 060.wav）解析为 MIDI 音符号，
+// 按 tmpl 生成键名；tmpl 支持 {note} 音符号与 {base} 去掉扩展名的文件名。
+// 文件名不以数字开头或音符号超过 127 时返回错误
+func midiKey(path, tmpl string) (string, error) {
+	base := fileKey(path)
+	n := 0
+	for n < len(base) && base[n] >= '0' && base[n] <= '9' {
+		n++
+	}
+	if n == 0 {
+		return "", fmt.Errorf("%s 的文件名不以数字开头，无法解析 MIDI 音符号", path)
+	}
+	note, err := strconv.Atoi(base[:n])
+	if err != nil || note > 127 {
+		return "", fmt.Errorf("%s 的 MIDI 音符号 %s 超出 0-127", path, base[:n])
+	}
+	return expandNameTemplate(tmpl, midiVars(base, note))
+}
+
+// midiVars 返回 MIDI 键名模板可用的占位符
+func midiVars(base string, note int) map[string]interface{} {
+	return map[string]interface{}{"base": base, "note": note}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestMidiKey(t *testing.T) {
+	tests := []struct {
+		path    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{"samples/60.wav", defaultMIDIKeyTemplate, "note_60", false},
+		// 前导零
+		{"060.wav", defaultMIDIKeyTemplate, "note_60", false},
+		{"000.wav", defaultMIDIKeyTemplate, "note_0", false},
+		{"127.wav", defaultMIDIKeyTemplate, "note_127", false},
+		// 数字之后的部分不参与解析，但保留在 {base} 中
+		{"036_kick.wav", "{note}-{base}", "36-036_kick", false},
+		{"60.wav", "n{note:03d}", "n060", false},
+		// 超出 0-127
+		{"128.wav", defaultMIDIKeyTemplate, "", true},
+		{"99999999999999999999.wav", defaultMIDIKeyTemplate, "", true},
+		// 不以数字开头
+		{"kick_36.wav", defaultMIDIKeyTemplate, "", true},
+		{"-1.wav", defaultMIDIKeyTemplate, "", true},
+		// 模板本身无效
+		{"60.wav", "{velocity}", "", true},
+		{"60.wav", "{base:d}", "", true},
+	}
+	for _, tt := range tests {
+		got, err := midiKey(tt.path, tt.tmpl)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("midiKey(%q, %q) = %q, 应返回错误", tt.path, tt.tmpl, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("midiKey(%q, %q) = %q, %v; want %q", tt.path, tt.tmpl, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultNameTemplate 是生成键名的默认模板，与早期的 clip_0、clip_1 … 命名一致
+const defaultNameTemplate = "clip_{index}"
+
+var placeholderRe = regexp.MustCompile(`\{(\w+)(?::([^}]*))?\}`)
+
+// verbRe 匹配占位符的格式部分：可选的标志、宽度、精度，加一个动词
+var verbRe = regexp.MustCompile(`^[-+# 0]*\d*(?:\.\d*)?([a-zA-Z])$`)
+
+// verbsFor 返回值类型可用的 printf 动词，如 {base:03d} 对字符串无效
+func verbsFor(v interface{}) string {
+	switch v.(type) {
+	case int:
+		return "bcdoOqxXUv"
+	case string:
+		return "sqxXv"
+	}
+	return "v"
+}
+
+// expandNameTemplate 替换模板中的 {name} 或 {name:格式} 占位符，
+// 格式为不带 % 的 printf 动词，如 {index:03d}；动词与值的类型不符时返回错误
+func expandNameTemplate(tmpl string, vars map[string]interface{}) (string, error) {
+	var err error
+	out := placeholderRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		sub := placeholderRe.FindStringSubmatch(m)
+		v, ok := vars[sub[1]]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("名称模板 %q 中有未知占位符 {%s}", tmpl, sub[1])
+			}
+			return m
+		}
+		if sub[2] != "" {
+			verb := verbRe.FindStringSubmatch(sub[2])
+			if verb == nil || !strings.Contains(verbsFor(v), verb[1]) {
+				if err == nil {
+					err = fmt.Errorf("名称模板 %q 中占位符 {%s} 的格式 %q 不适用于 %T 类型的值", tmpl, sub[1], sub[2], v)
+				}
+				return m
+			}
+			return fmt.Sprintf("%"+sub[2], v)
+		}
+		return fmt.Sprint(v)
+	})
+	return out, err
+}
+
+// nameVars 返回名称模板可用的占位符：{base} 来源片段键名，{index} 序号，{rate} 采样率
+func nameVars(base string, index, rate int) map[string]interface{} {
+	return map[string]interface{}{"base": base, "index": index, "rate": rate}
+}
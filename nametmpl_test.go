@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestExpandNameTemplate(t *testing.T) {
+	vars := nameVars("kick", 7, 44100)
+	tests := []struct {
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{"clip_{index}", "clip_7", false},
+		{"{base}_{index:03d}", "kick_007", false},
+		{"{base:-6s}|", "kick  |", false},
+		{"{index:x}@{rate}", "7@44100", false},
+		{"{base:q}", `"kick"`, false},
+		{"{name}", "", true},
+		// 动词与值的类型不符
+		{"{base:03d}", "", true},
+		{"{index:s}", "", true},
+		// 缺少动词或带有多余字符
+		{"{index:03}", "", true},
+		{"{index:%d}", "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandNameTemplate(tt.tmpl, vars)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expandNameTemplate(%q) = %q, 应返回错误", tt.tmpl, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expandNameTemplate(%q) = %q, %v, want %q", tt.tmpl, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"2_x.wav", "10_x.wav", true},
+		{"10_x.wav", "2_x.wav", false},
+		{"clip9", "clip10", true},
+		{"a2b", "a10a", true},
+		// 前导零不影响数值大小
+		{"007.wav", "10.wav", true},
+		{"010.wav", "9.wav", false},
+		// 数值相同时按原字符串比较，保证顺序确定
+		{"01.wav", "1.wav", true},
+		{"1.wav", "01.wav", false},
+		{"a.wav", "a.wav", false},
+		// 一方是另一方的前缀
+		{"clip", "clip1", true},
+		{"clip1", "clip", false},
+		{"a", "b", true},
+		{"B", "a", true},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("naturalLess(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNaturalLessSort(t *testing.T) {
+	names := []string{"10.wav", "1.wav", "02.wav", "2.wav", "001.wav", "100.wav", "9.wav"}
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	want := []string{"001.wav", "1.wav", "02.wav", "2.wav", "9.wav", "10.wav", "100.wav"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sorted = %v, want %v", names, want)
+	}
+}
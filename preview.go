@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+
+	"github.com/go-audio/audio"
+)
+
+// findSprite 按键名查找片段
+func findSprite(sprites []sprite, key string) (sprite, bool) {
+	for _, sp := range sprites {
+		if sp.key == key {
+			return sp, true
+		}
+	}
+	return sprite{}, false
+}
+
+// previewSprite 把片段 sp 的采样写成临时 WAV，用 ffplay 播放一次，播放结束后返回
+func previewSprite(ctx context.Context, buf *audio.IntBuffer, sp sprite) error {
+	player, err := exec.LookPath("ffplay")
+	if err != nil {
+		return fmt.Errorf("没有可用的播放后端: 未找到 ffplay，请安装 ffmpeg 并确认 ffplay 位于 PATH 中")
+	}
+
+	f, err := ioutil.TempFile("", "audiosprite-preview-*.wav")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	tmp := f.Name()
+	addTemp(tmp)
+	defer removeTemp(tmp)
+	writeWAV(tmp, frameSlice(buf, sp.start, sp.end), buf.Format.SampleRate)
+
+	cmd := exec.CommandContext(ctx, player, "-nodisp", "-autoexit", "-loglevel", "error", tmp)
+	if err := runCommand(cmd); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("ffplay 播放失败: %v", err)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+
+	"github.com/go-audio/audio"
+)
+
+// 报告中每个片段的波形缩略图尺寸
+const (
+	sparklineWidth  = 160
+	sparklineHeight = 24
+)
+
+// reportEntry 是 HTML 报告中的一行
+type reportEntry struct {
+	Key       string
+	Resource  string
+	Start     float64
+	End       float64
+	Duration  float64
+	Loop      bool
+	Sparkline string
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="zh">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 12px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+svg path { stroke: #3366cc; stroke-width: 1; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Entries}} 个片段，音频：{{range $i, $r := .Resources}}{{if $i}}, {{end}}{{$r}}{{end}}</p>
+<table>
+<tr><th>key</th><th>文件</th><th>start (s)</th><th>end (s)</th><th>时长 (s)</th><th>loop</th>{{if .Sparklines}}<th>波形</th>{{end}}</tr>
+{{- range .Entries}}
+<tr><td>{{.Key}}</td><td>{{.Resource}}</td><td class="num">{{printf "%.3f" .Start}}</td><td class="num">{{printf "%.3f" .End}}</td><td class="num">{{printf "%.3f" .Duration}}</td><td>{{if .Loop}}✔{{end}}</td>
+{{- if $.Sparklines}}<td><svg width="{{$.Width}}" height="{{$.Height}}"><path d="{{.Sparkline}}"/></svg></td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// sparklinePath 把 buf 中 [start, end) 帧的峰值画成 SVG 路径，每列一条竖线
+func sparklinePath(buf *audio.IntBuffer, start, end int) string {
+	clip := frameSlice(buf, start, end)
+	mid := float64(sparklineHeight) / 2
+	var sb strings.Builder
+	for x, p := range waveformPeaks(clip, sparklineWidth) {
+		top := mid - p.max*mid
+		bottom := mid - p.min*mid
+		if bottom-top < 1 {
+			bottom = top + 1
+		}
+		fmt.Fprintf(&sb, "M%d.5 %.1fV%.1f", x, top, bottom)
+	}
+	return sb.String()
+}
+
+// writeReport 生成自包含的 HTML 报告，按追加顺序列出每个片段；
+// buf 非 nil 时附带波形缩略图
+func writeReport(path, title string, sprites []sprite, spritemap map[string]SpriteMapEntry, resources []string, buf *audio.IntBuffer) error {
+	data := struct {
+		Title         string
+		Resources     []string
+		Entries       []reportEntry
+		Sparklines    bool
+		Width, Height int
+	}{
+		Title:      title,
+		Resources:  resources,
+		Sparklines: buf != nil,
+		Width:      sparklineWidth,
+		Height:     sparklineHeight,
+	}
+	for _, sp := range sprites {
+		entry := spritemap[sp.key]
+		row := reportEntry{
+			Key:      sp.key,
+			Resource: resources[entry.Resource],
+			Start:    entry.Start,
+			End:      entry.End,
+			Duration: entry.End - entry.Start,
+			Loop:     sp.loop,
+		}
+		if buf != nil {
+			row.Sparkline = sparklinePath(buf, sp.start, sp.end)
+		}
+		data.Entries = append(data.Entries, row)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ffmpegRetries 对应 -ffmpeg-retries：临时性失败后最多重试的次数
+var ffmpegRetries int
+
+// retryBaseDelay 是第一次重试前的等待时间，之后每次翻倍
+const retryBaseDelay = 500 * time.Millisecond
+
+// transientMarkers 是 ffmpeg 输出中表示系统资源暂时不足的信息
+var transientMarkers = []string{
+	"Resource temporarily unavailable",
+	"Cannot allocate memory",
+	"Too many open files",
+}
+
+// transientFFmpegError 判断一次 ffmpeg 失败是否可能重试成功：
+// 进程启动失败（找不到或无权执行除外）、被信号终止（如内存不足被杀）
+// 或输出中带有资源不足的信息。其余非零退出视为输入或编码错误
+func transientFFmpegError(err error, out []byte) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return !errors.Is(err, exec.ErrNotFound) && !errors.Is(err, os.ErrPermission)
+	}
+	if exitErr.ExitCode() == -1 {
+		return true
+	}
+	for _, m := range transientMarkers {
+		if strings.Contains(string(out), m) {
+			return true
+		}
+	}
+	return false
+}
+
+// runFFmpeg 运行 ffmpeg，临时性失败时按 -ffmpeg-retries 退避重试；
+// ctx 取消后不再重试
+func runFFmpeg(ctx context.Context, args ...string) ([]byte, error) {
+	return retryFFmpeg(ctx, func() ([]byte, error) {
+		return runFFmpegOnce(ctx, args...)
+	})
+}
+
+// retryFFmpeg 执行一次 ffmpeg 调用 run，临时性失败时按 -ffmpeg-retries 退避重试。
+// run 返回 ffmpeg 的诊断输出（用于判断是否临时性失败）和错误，每次重试都应从头开始
+func retryFFmpeg(ctx context.Context, run func() ([]byte, error)) ([]byte, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		out, err := run()
+		if err == nil || ctx.Err() != nil || attempt > ffmpegRetries || !transientFFmpegError(err, out) {
+			return out, err
+		}
+		debugf("ffmpeg 临时失败（%v），%v 后第 %d/%d 次重试", err, delay, attempt, ffmpegRetries)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return out, err
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+// reverseFrames 按帧倒序返回 data 的副本，保持每帧内的声道顺序不变
+func reverseFrames(data []int, ch int) []int {
+	frames := len(data) / ch
+	out := make([]int, frames*ch)
+	for i := 0; i < frames; i++ {
+		copy(out[(frames-1-i)*ch:(frames-i)*ch], data[i*ch:(i+1)*ch])
+	}
+	return out
+}
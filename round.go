@@ -0,0 +1,42 @@
+package main
+
+import "math"
+
+// 秒数量化方式：播放器按 t*rate 换算回帧时使用的取整方式
+const (
+	roundRaw     = ""
+	roundNearest = "nearest"
+	roundFloor   = "floor"
+	roundCeil    = "ceil"
+)
+
+// frameSeconds 返回第 frame 帧对应的秒数。mode 非空时微调结果，
+// 保证 t*rate 按 mode 取整后恰好等于 frame，避免浮点误差落到相邻帧
+func frameSeconds(frame, rate int, mode string) float64 {
+	t := float64(frame) / float64(rate)
+	var quantize func(float64) float64
+	switch mode {
+	case roundNearest:
+		quantize = math.Round
+	case roundFloor:
+		quantize = math.Floor
+	case roundCeil:
+		quantize = math.Ceil
+	default:
+		return t
+	}
+	target := float64(frame)
+	// 误差最多几个 ulp，逐步逼近即可
+	for i := 0; i < 64; i++ {
+		got := quantize(t * float64(rate))
+		if got == target {
+			break
+		}
+		if got < target {
+			t = math.Nextafter(t, math.Inf(1))
+		} else {
+			t = math.Nextafter(t, math.Inf(-1))
+		}
+	}
+	return t
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrameSeconds(t *testing.T) {
+	const rate = 44100
+	quantize := map[string]func(float64) float64{
+		roundNearest: math.Round,
+		roundFloor:   math.Floor,
+		roundCeil:    math.Ceil,
+	}
+	// 第一秒内的每一帧，以及几个较大的帧数；其中有些帧不经微调时
+	// t*rate 按 floor/ceil 会落到相邻帧
+	frames := []int{132299, 1234567, 44100 * 3600}
+	for frame := 0; frame <= rate; frame++ {
+		frames = append(frames, frame)
+	}
+	for mode, q := range quantize {
+		for _, frame := range frames {
+			sec := frameSeconds(frame, rate, mode)
+			if got := q(sec * rate); got != float64(frame) {
+				t.Fatalf("%s: frameSeconds(%d) = %v，换算回 %v 帧", mode, frame, sec, got)
+			}
+			// 微调只有几个 ulp
+			if d := math.Abs(sec - float64(frame)/rate); d > 1e-9 {
+				t.Fatalf("%s: frameSeconds(%d) 偏离 %v", mode, frame, d)
+			}
+		}
+	}
+}
+
+func TestFrameSecondsRaw(t *testing.T) {
+	if got := frameSeconds(22050, 44100, roundRaw); got != 0.5 {
+		t.Errorf("frameSeconds(22050, raw) = %v, want 0.5", got)
+	}
+	if got := frameSeconds(1, 44100, roundRaw); got != 1.0/44100 {
+		t.Errorf("frameSeconds(1, raw) = %v, want %v", got, 1.0/44100)
+	}
+}
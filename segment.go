@@ -0,0 +1,61 @@
+package main
+
+import "github.com/go-audio/audio"
+
+// detectSegments 在 buf 中查找被静音隔开的有声段，返回各段的帧区间 [start, end)。
+// 一帧所有声道的幅度都不超过 threshold 即视为静音；
+// 只有连续静音达到 minSilence 帧才作为分隔，更短的停顿仍归入同一段。
+func detectSegments(buf *audio.IntBuffer, threshold, minSilence int) [][2]int {
+	ch := buf.Format.NumChannels
+	frames := len(buf.Data) / ch
+	silent := func(i int) bool {
+		for _, v := range buf.Data[i*ch : (i+1)*ch] {
+			if sampleAmplitude(v, buf.SourceBitDepth) > threshold {
+				return false
+			}
+		}
+		return true
+	}
+
+	var segments [][2]int
+	segStart := -1 // 当前段的起点，-1 表示尚未进入有声段
+	lastSound := -1
+	for i := 0; i < frames; i++ {
+		if silent(i) {
+			if segStart >= 0 && i-lastSound >= minSilence {
+				segments = append(segments, [2]int{segStart, lastSound + 1})
+				segStart = -1
+			}
+			continue
+		}
+		if segStart < 0 {
+			segStart = i
+		}
+		lastSound = i
+	}
+	if segStart >= 0 {
+		segments = append(segments, [2]int{segStart, lastSound + 1})
+	}
+	return segments
+}
+
+// segmentSprites 把检测到的有声段转换为片段，键名由名称模板生成；
+// {base} 为该段起点所在的原片段键名
+func segmentSprites(segments [][2]int, source []sprite, tmpl string, rate int) ([]sprite, error) {
+	sprites := make([]sprite, len(segments))
+	for i, seg := range segments {
+		base, src := "", ""
+		for _, sp := range source {
+			if seg[0] >= sp.start && seg[0] < sp.end {
+				base, src = sp.key, sp.source
+				break
+			}
+		}
+		key, err := expandNameTemplate(tmpl, nameVars(base, i, rate))
+		if err != nil {
+			return nil, err
+		}
+		sprites[i] = sprite{key: key, start: seg[0], end: seg[1], source: src}
+	}
+	return sprites, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectSegments(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []int
+		minSilence int
+		want       [][2]int
+	}{
+		{
+			name:       "静音恰好达到 minSilence 帧时分段",
+			data:       []int{1000, 0, 0, 1000},
+			minSilence: 2,
+			want:       [][2]int{{0, 1}, {3, 4}},
+		},
+		{
+			name:       "静音比 minSilence 少一帧时不分段",
+			data:       []int{1000, 0, 1000},
+			minSilence: 2,
+			want:       [][2]int{{0, 3}},
+		},
+		{
+			name:       "末尾的有声段一直延续到结尾",
+			data:       []int{0, 0, 1000, -1000},
+			minSilence: 1,
+			want:       [][2]int{{2, 4}},
+		},
+		{
+			name:       "末尾静音不足 minSilence 时最后一段在最后的有声帧处结束",
+			data:       []int{1000, 0, 0, 1000, 1000, 0},
+			minSilence: 2,
+			want:       [][2]int{{0, 1}, {3, 5}},
+		},
+		{
+			name:       "阈值以内的幅度视为静音",
+			data:       []int{100, -100, 1000, 100},
+			minSilence: 1,
+			want:       [][2]int{{2, 3}},
+		},
+		{
+			name:       "全部静音",
+			data:       []int{0, 0, 0},
+			minSilence: 1,
+			want:       nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectSegments(monoBuffer(16, tt.data...), 100, tt.minSilence)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectSegments(%v) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSegmentsStereo(t *testing.T) {
+	// 任一声道有声，该帧即不是静音
+	buf := stereoBuffer(16, 0, 0, 0, 1000, 0, 0, 0, 0, 1000, 0)
+	got := detectSegments(buf, 100, 2)
+	if want := [][2]int{{1, 2}, {4, 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("detectSegments = %v, want %v", got, want)
+	}
+}
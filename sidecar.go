@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-audio/audio"
+)
+
+// sidecarMeta 对应输入文件旁的 `<file>.meta`，用于纠正 WAV 头中错误的采样率/声道数
+type sidecarMeta struct {
+	SampleRate int `json:"sampleRate"`
+	Channels   int `json:"channels"`
+}
+
+// loadSidecarMeta 读取 path 对应的 .meta 文件，不存在时返回 nil
+func loadSidecarMeta(path string) (*sidecarMeta, error) {
+	data, err := ioutil.ReadFile(path + ".meta")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta sidecarMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("%s.meta: %v", path, err)
+	}
+	if meta.SampleRate < 0 || meta.Channels < 0 {
+		return nil, fmt.Errorf("%s.meta: 采样率和声道数不能为负数", path)
+	}
+	return &meta, nil
+}
+
+// loadSidecarJSON 读取 path 对应的 `<file>.json`，返回其中的全部字段，不存在时返回 nil；
+// 文件内容必须是 JSON 对象
+func loadSidecarJSON(path string) (map[string]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(path + ".json")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%s.json: 应为 JSON 对象: %v", path, err)
+	}
+	return fields, nil
+}
+
+// apply 用 sidecar 中非零的字段覆盖 buf 的格式
+func (m *sidecarMeta) apply(buf *audio.IntBuffer) {
+	if m.SampleRate > 0 {
+		buf.Format.SampleRate = m.SampleRate
+	}
+	if m.Channels > 0 {
+		buf.Format.NumChannels = m.Channels
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// atlasPart 是拆分后的一个输出文件在 outBuf 中的帧区间
+type atlasPart struct {
+	start, end int
+	// format 是该文件的输出格式
+	format string
+}
+
+// 各压缩格式的估算码率（字节/秒）：mp3 对应 libmp3lame -qscale:a 2 的约 190kbps，
+// ogg 对应 libvorbis 默认质量的约 112kbps
+var compressedBytesPerSecond = map[string]float64{
+	"mp3": 190000 / 8,
+	"ogg": 112000 / 8,
+}
+
+// wavHeaderSize 是 go-audio 写出的 WAV 头长度
+const wavHeaderSize = 44
+
+// estimateSize 估算 frames 帧音频以 format 输出后的文件字节数
+func estimateSize(frames, rate, ch, bits int, format string) int64 {
+	if bps, ok := compressedBytesPerSecond[format]; ok {
+		return int64(float64(frames) / float64(rate) * bps)
+	}
+	return wavHeaderSize + int64(frames)*int64(ch)*int64(bits/8)
+}
+
+// parseSize 解析 `500K`、`2M`、`1G` 或纯字节数形式的大小（按 1024 进制）
+func parseSize(s string) (int64, error) {
+	u := strings.ToUpper(strings.TrimSpace(s))
+	u = strings.TrimSuffix(u, "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(u, "K"):
+		mult, u = 1<<10, strings.TrimSuffix(u, "K")
+	case strings.HasSuffix(u, "M"):
+		mult, u = 1<<20, strings.TrimSuffix(u, "M")
+	case strings.HasSuffix(u, "G"):
+		mult, u = 1<<30, strings.TrimSuffix(u, "G")
+	}
+	n, err := strconv.ParseFloat(u, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的大小: %s", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// splitParts 按片段边界把 within 区段拆分成若干个估算大小不超过 limit 的输出文件，
+// 拆分出的文件沿用 within 的格式。
+// 拆分点总在某个片段的起点，片段之间的间隔归前一个文件；
+// 单个片段本身超过 limit 时独占一个文件。
+func splitParts(sprites []sprite, within atlasPart, limit int64, size func(frames int) int64) []atlasPart {
+	parts := []atlasPart{{start: within.start, format: within.format}}
+	for _, sp := range sprites {
+		if sp.start < within.start || sp.start >= within.end {
+			continue
+		}
+		cur := &parts[len(parts)-1]
+		if sp.start > cur.start && size(sp.end-cur.start) > limit {
+			cur.end = sp.start
+			parts = append(parts, atlasPart{start: sp.start, format: within.format})
+		}
+	}
+	parts[len(parts)-1].end = within.end
+	return parts
+}
+
+// formatGroups 把 [0, total) 按片段的输出格式切成连续的区段，每段对应一个音频文件。
+// 调用前输入已按格式分组（见 groupByFormat），同一格式的片段在缓冲中是连续的
+func formatGroups(sprites []sprite, total int) []atlasPart {
+	var parts []atlasPart
+	for _, sp := range sprites {
+		if len(parts) > 0 && parts[len(parts)-1].format == sp.format {
+			continue
+		}
+		if len(parts) > 0 {
+			parts[len(parts)-1].end = sp.start
+		}
+		start := sp.start
+		if len(parts) == 0 {
+			start = 0
+		}
+		parts = append(parts, atlasPart{start: start, format: sp.format})
+	}
+	parts[len(parts)-1].end = total
+	return parts
+}
+
+// assignResources 把每个片段所在的文件序号写入 sprites[i].resource。
+// 去重后的片段可能复用前面的区间，按起点所在的文件分配下标
+func assignResources(sprites []sprite, parts []atlasPart) {
+	for i := range sprites {
+		for p := len(parts) - 1; p >= 0; p-- {
+			if sprites[i].start >= parts[p].start {
+				sprites[i].resource = p
+				break
+			}
+		}
+	}
+}
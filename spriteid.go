@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-audio/audio"
+)
+
+// spriteParams 是参与计算 spriteId 的内容，字段顺序固定。
+// 拼接后的 PCM 和清单已经体现了所有改变采样或时间的选项（增益、淡入淡出、裁剪、
+// 去重、间隔、拆分等），其余字段是只在编码阶段生效、不反映在二者中的参数
+type spriteParams struct {
+	Inputs   []string `json:"inputs"`
+	Rate     int      `json:"rate"`
+	Bits     int      `json:"bits"`
+	Channels int      `json:"channels"`
+	// Audio 是拼接后 PCM 的哈希，见 pcmDigest
+	Audio string `json:"audio"`
+	// Manifest 是不含 spriteId 的主 JSON 的哈希
+	Manifest         string             `json:"manifest"`
+	Formats          []string           `json:"formats"`
+	IntermediateBits int                `json:"intermediateBits"`
+	OggQuality       *float64           `json:"oggQuality"`
+	Loudness         map[string]float64 `json:"loudness"`
+	Metadata         map[string]string  `json:"metadata"`
+	WAVFact          bool               `json:"wavFact"`
+	WAVBlockAlign    int                `json:"wavBlockAlign"`
+	WAVByteRate      int                `json:"wavByteRate"`
+}
+
+// inputIDs 返回按输入顺序排列的输入标识：统一为 / 分隔的路径，带截取区间时附上 @in-out
+func inputIDs(inputs []clipSpec) []string {
+	ids := make([]string, len(inputs))
+	for i, in := range inputs {
+		ids[i] = filepath.ToSlash(in.path)
+		if in.trim != nil {
+			ids[i] += fmt.Sprintf("@%g-%g", in.trim.in, in.trim.out)
+		}
+	}
+	return ids
+}
+
+// pcmDigest 返回 buf 中全部采样（按 int32 小端序）的 SHA-256 十六进制串
+func pcmDigest(buf *audio.IntBuffer) string {
+	h := sha256.New()
+	var b [4]byte
+	for _, v := range buf.Data {
+		binary.LittleEndian.PutUint32(b[:], uint32(int32(v)))
+		h.Write(b[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// manifestDigest 返回序列化后清单的 SHA-256 十六进制串
+func manifestDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// spriteID 返回 p 的 SHA-256 前 16 个十六进制字符，
+// 输入、音频、清单与编码参数都相同的构建总得到相同的 id
+func spriteID(p spriteParams) string {
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestSpriteIDStable(t *testing.T) {
+	p := spriteParams{
+		Inputs:   []string{"a.wav", "b.wav"},
+		Rate:     44100,
+		Bits:     16,
+		Channels: 1,
+		Audio:    pcmDigest(monoBuffer(16, 1, 2, 3)),
+		Manifest: manifestDigest([]byte(`{"resources":["x.wav"]}`)),
+		Formats:  []string{"mp3"},
+		Loudness: map[string]float64{"mp3": -1, "ogg": 1},
+	}
+	id := spriteID(p)
+	if len(id) != 16 {
+		t.Errorf("spriteID 长度 = %d, want 16", len(id))
+	}
+	if again := spriteID(p); again != id {
+		t.Errorf("相同参数得到不同的 id: %s, %s", id, again)
+	}
+}
+
+func TestSpriteIDChanges(t *testing.T) {
+	base := spriteParams{
+		Inputs:   []string{"a.wav"},
+		Rate:     44100,
+		Bits:     16,
+		Channels: 1,
+		Audio:    pcmDigest(monoBuffer(16, 1, 2, 3)),
+		Manifest: manifestDigest([]byte(`{"spritemap":{"a":{"start":0,"end":1}}}`)),
+		Formats:  []string{"ogg"},
+	}
+	quality := 5.0
+	changes := map[string]func(*spriteParams){
+		// 如 -gain、-fadein：采样不同
+		"audio": func(p *spriteParams) { p.Audio = pcmDigest(monoBuffer(16, 1, 2, 4)) },
+		// 如 -round、-time-base：清单不同
+		"manifest":    func(p *spriteParams) { p.Manifest = manifestDigest([]byte(`{"spritemap":{"a":{"start":0,"end":2}}}`)) },
+		"ogg-quality": func(p *spriteParams) { p.OggQuality = &quality },
+		"loudness":    func(p *spriteParams) { p.Loudness = map[string]float64{"ogg": -2} },
+		"metadata":    func(p *spriteParams) { p.Metadata = map[string]string{"title": "x"} },
+		"wav-fact":    func(p *spriteParams) { p.WAVFact = true },
+	}
+	id := spriteID(base)
+	for name, change := range changes {
+		p := base
+		change(&p)
+		if spriteID(p) == id {
+			t.Errorf("%s 变化后 spriteId 不变", name)
+		}
+	}
+}
+
+func TestPCMDigest(t *testing.T) {
+	if pcmDigest(monoBuffer(16, 1, -1)) == pcmDigest(monoBuffer(16, -1, 1)) {
+		t.Error("采样顺序不同时摘要相同")
+	}
+	if pcmDigest(monoBuffer(16, 0)) == pcmDigest(monoBuffer(16, 0, 0)) {
+		t.Error("采样数不同时摘要相同")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// writeSummary 按追加顺序输出每个片段的 `key start end loop`，便于 awk/grep 处理。
+// 时间相对片段所在的输出文件 parts[sp.resource]；输出被拆分为多个文件时
+// 末尾再加一列 resource 下标
+func writeSummary(w io.Writer, sprites []sprite, parts []atlasPart, rate int) error {
+	for _, sp := range sprites {
+		offset := parts[sp.resource].start
+		line := fmt.Sprintf("%s %.3f %.3f %t", sp.key,
+			float64(sp.start-offset)/float64(rate), float64(sp.end-offset)/float64(rate), sp.loop)
+		if len(parts) > 1 {
+			line += fmt.Sprintf(" %d", sp.resource)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteSummary(t *testing.T) {
+	sprites := []sprite{
+		{key: "a", start: 0, end: 100},
+		{key: "b", start: 100, end: 300, loop: true},
+	}
+	var out bytes.Buffer
+	if err := writeSummary(&out, sprites, []atlasPart{{start: 0, end: 300}}, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a 0.000 0.100 false\nb 0.100 0.300 true\n"; out.String() != want {
+		t.Errorf("summary =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteSummarySplitParts(t *testing.T) {
+	// 拆分后 b 位于第二个文件，时间从该文件开头算起
+	parts := []atlasPart{{start: 0, end: 100}, {start: 100, end: 300}}
+	sprites := []sprite{
+		{key: "a", start: 0, end: 100},
+		{key: "b", start: 100, end: 300, resource: 1},
+	}
+	var out bytes.Buffer
+	if err := writeSummary(&out, sprites, parts, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a 0.000 0.100 false 0\nb 0.000 0.200 false 1\n"; out.String() != want {
+		t.Errorf("summary =\n%s\nwant\n%s", out.String(), want)
+	}
+}
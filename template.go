@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateData 是 -template 模板可用的数据
+type templateData struct {
+	Resources []string
+	// Entries 按片段在 sprite 中的追加顺序排列
+	Entries []templateEntry
+}
+
+// templateEntry 是模板中的一个片段，时间单位为秒，相对其所在的 Resources[Resource]
+type templateEntry struct {
+	Key      string
+	Start    float64
+	End      float64
+	Loop     bool
+	Resource int
+}
+
+// templateOutputPath 推导模板输出路径：去掉 .gotmpl/.tmpl 后缀后沿用剩余的扩展名，
+// 例如 manifest.lua.gotmpl 输出为 <基名>.lua；没有扩展名时使用 .txt
+func templateOutputPath(outBase, tmplPath string) string {
+	name := filepath.Base(tmplPath)
+	for _, suffix := range []string{".gotmpl", ".tmpl"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	ext := filepath.Ext(name)
+	if ext == "" {
+		ext = ".txt"
+	}
+	return outBase + ext
+}
+
+// writeTemplate 用 text/template 渲染 tmplPath 并写入 outPath
+func writeTemplate(tmplPath, outPath string, sprites []sprite, spritemap map[string]SpriteMapEntry, resources []string) error {
+	tmpl, err := template.ParseFiles(tmplPath)
+	if err != nil {
+		return err
+	}
+	data := templateData{Resources: resources}
+	for _, sp := range sprites {
+		entry := spritemap[sp.key]
+		data.Entries = append(data.Entries, templateEntry{
+			Key:      sp.key,
+			Start:    entry.Start,
+			End:      entry.End,
+			Loop:     entry.looping(),
+			Resource: entry.Resource,
+		})
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
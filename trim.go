@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-audio/audio"
+)
+
+// silenceDBFS 是判定“接近静音”的默认阈值
+const silenceDBFS = -60.0
+
+// dbToLinear 将分贝值换算为线性增益
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// sampleAmplitude 返回采样值相对零点的绝对幅度，8 位采样为无符号数需先去掉偏移
+func sampleAmplitude(v, bits int) int {
+	if bits == 8 {
+		v -= 128
+	}
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// dbfsToAmplitude 把 dBFS 换算成 bits 位深下的整数幅度，满幅取 2^(bits-1)，
+// 因此同一 dBFS 在 16 位和 24 位输入上对应相同的相对电平
+func dbfsToAmplitude(dbfs float64, bits int) int {
+	return int(float64(int(1)<<(bits-1)) * dbToLinear(dbfs))
+}
+
+// silenceThreshold 把默认的 silenceDBFS 换算成 bits 位深下的整数幅度
+func silenceThreshold(bits int) int {
+	return dbfsToAmplitude(silenceDBFS, bits)
+}
+
+// trimTrailingSilence 从输出中最后一个片段的末尾往回找到最后一帧幅度超过
+// thresholdDBFS 的位置，在那里截断 buf，并把该片段的 end 改为截断点。
+// 循环片段、带循环区间的片段和 -fixed-length 补齐的片段不裁剪；截断点不早于该片段的 start 和其他片段的 end，
+// 整段都是静音的片段保持原样，只去掉它之后的帧。
+func trimTrailingSilence(buf *audio.IntBuffer, sprites []sprite, thresholdDBFS float64) {
+	ch := buf.Format.NumChannels
+	threshold := dbfsToAmplitude(thresholdDBFS, buf.SourceBitDepth)
+	frames := len(buf.Data) / ch
+
+	last := -1
+	for i, sp := range sprites {
+		if last < 0 || sp.end > sprites[last].end {
+			last = i
+		}
+	}
+	if last < 0 {
+		return
+	}
+	start, end := sprites[last].start, sprites[last].end
+	// 与最后一个片段共用区间的片段（如 -dedup 复用的）一起裁剪；其余片段的 end 是下限
+	floor := start
+	for _, sp := range sprites {
+		if sp.start == start && sp.end == end {
+			if sp.loop || sp.loopRegion != nil || sp.padded {
+				floor = end
+			}
+		} else if sp.end > floor {
+			floor = sp.end
+		}
+	}
+
+	cut := frames
+	for cut > floor {
+		silent := true
+		for _, v := range buf.Data[(cut-1)*ch : cut*ch] {
+			if sampleAmplitude(v, buf.SourceBitDepth) > threshold {
+				silent = false
+				break
+			}
+		}
+		if !silent {
+			break
+		}
+		cut--
+	}
+	if cut == start && end > start {
+		cut = end
+	}
+	buf.Data = buf.Data[:cut*ch]
+	for i := range sprites {
+		if sprites[i].start == start && sprites[i].end == end && cut < end {
+			sprites[i].end = cut
+		}
+	}
+}
+
+// padFixedLength 在 data 末尾补静音到 fixed 帧，并把 sp 标记为已补齐，
+// 使 trimTrailingSilence 不会裁掉补齐的部分。fixed 为 0（未指定 -fixed-length）时原样返回
+func padFixedLength(sp *sprite, data []int, fixed, ch, bits int) []int {
+	if fixed == 0 {
+		return data
+	}
+	sp.padded = true
+	return append(data, silenceFrames(fixed-len(data)/ch, ch, bits)...)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-audio/audio"
+)
+
+// monoBuffer 返回以 data 为采样的单声道缓冲
+func monoBuffer(bits int, data ...int) *audio.IntBuffer {
+	return &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 1, SampleRate: 44100},
+		Data:           data,
+		SourceBitDepth: bits,
+	}
+}
+
+func TestTrimTrailingSilence(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []int
+		sprites    []sprite
+		wantFrames int
+		wantEnds   []int
+	}{
+		{
+			name:       "最后一个片段尾部的静音被裁掉，end 随之提前",
+			data:       []int{1000, 0, 1000, 1000, 0, 0},
+			sprites:    []sprite{{key: "a", start: 0, end: 2}, {key: "b", start: 2, end: 6}},
+			wantFrames: 4,
+			wantEnds:   []int{2, 4},
+		},
+		{
+			name:       "最后一个片段之后的静音一并裁掉",
+			data:       []int{1000, 1000, 0, 0, 0},
+			sprites:    []sprite{{key: "a", start: 0, end: 2}},
+			wantFrames: 2,
+			wantEnds:   []int{2},
+		},
+		{
+			name:       "共用区间的片段一起缩短",
+			data:       []int{1000, 0, 0},
+			sprites:    []sprite{{key: "a", start: 0, end: 3}, {key: "dup", start: 0, end: 3}},
+			wantFrames: 1,
+			wantEnds:   []int{1, 1},
+		},
+		{
+			name:       "整段静音的最后一个片段不被压缩为零长度",
+			data:       []int{1000, 1000, 0, 0, 0},
+			sprites:    []sprite{{key: "a", start: 0, end: 2}, {key: "silent", start: 2, end: 4}},
+			wantFrames: 4,
+			wantEnds:   []int{2, 4},
+		},
+		{
+			name:       "循环片段不被裁剪",
+			data:       []int{1000, 0, 0, 0},
+			sprites:    []sprite{{key: "loop", start: 0, end: 3, loop: true}},
+			wantFrames: 3,
+			wantEnds:   []int{3},
+		},
+		{
+			name:       "带循环区间的片段不被裁剪",
+			data:       []int{1000, 0, 0},
+			sprites:    []sprite{{key: "pad", start: 0, end: 3, loopRegion: &loopRegion{start: 0, end: 0.00001}}},
+			wantFrames: 3,
+			wantEnds:   []int{3},
+		},
+		{
+			name:       "非静音的尾部不裁",
+			data:       []int{1000, 0, 1000},
+			sprites:    []sprite{{key: "a", start: 0, end: 3}},
+			wantFrames: 3,
+			wantEnds:   []int{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := monoBuffer(16, tt.data...)
+			want := append([]sprite(nil), tt.sprites...)
+			trimTrailingSilence(buf, tt.sprites, silenceDBFS)
+			if len(buf.Data) != tt.wantFrames {
+				t.Errorf("裁剪后 %d 帧, want %d", len(buf.Data), tt.wantFrames)
+			}
+			for i, sp := range tt.sprites {
+				if sp.start != want[i].start || sp.end != tt.wantEnds[i] {
+					t.Errorf("片段 %s 变为 [%d, %d), want [%d, %d)", sp.key, sp.start, sp.end, want[i].start, tt.wantEnds[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDbfsToAmplitude(t *testing.T) {
+	tests := []struct {
+		dbfs float64
+		bits int
+		want int
+	}{
+		{0, 16, 32768},
+		{-6, 16, 16422},
+		{-60, 16, 32},
+		{-60, 24, 8388},
+		{-40, 24, 83886},
+	}
+	for _, tt := range tests {
+		if got := dbfsToAmplitude(tt.dbfs, tt.bits); got != tt.want {
+			t.Errorf("dbfsToAmplitude(%g, %d) = %d, want %d", tt.dbfs, tt.bits, got, tt.want)
+		}
+	}
+}
+
+func TestTrimEquivalentAcrossBitDepths(t *testing.T) {
+	// 同一段信号分别以 16 位和 24 位表示：-50 dBFS 的尾音高于 -60 dBFS 阈值，
+	// -70 dBFS 的尾音低于阈值，两种位深下裁剪到的位置应相同
+	levels := []float64{-6, -50, -70, -70}
+	for _, bits := range []int{16, 24} {
+		full := float64(int(1) << (bits - 1))
+		data := make([]int, len(levels))
+		for i, db := range levels {
+			data[i] = int(full * dbToLinear(db))
+		}
+		buf := monoBuffer(bits, data...)
+		trimTrailingSilence(buf, []sprite{{key: "a", start: 0, end: 1}}, silenceDBFS)
+		if len(buf.Data) != 2 {
+			t.Errorf("%d 位: 裁剪后 %d 帧, want 2", bits, len(buf.Data))
+		}
+	}
+}
+
+func TestTrimKeepsFixedLengthPadding(t *testing.T) {
+	// 按 -fixed-length 的路径把每个片段补到 4 帧后拼接，最后一个片段的补齐部分全是静音
+	clips := map[string][]int{"a": {1000, 1000}, "tail": {1000}}
+	build := func(fixed int) (*audio.IntBuffer, []sprite) {
+		buf := monoBuffer(16)
+		var sprites []sprite
+		for _, key := range []string{"a", "tail"} {
+			sp := sprite{key: key}
+			data := padFixedLength(&sp, append([]int(nil), clips[key]...), fixed, 1, 16)
+			sp.start = len(buf.Data)
+			buf.Data = append(buf.Data, data...)
+			sp.end = len(buf.Data)
+			sprites = append(sprites, sp)
+		}
+		return buf, sprites
+	}
+
+	buf, sprites := build(4)
+	trimTrailingSilence(buf, sprites, silenceDBFS)
+	if len(buf.Data) != 8 {
+		t.Errorf("裁剪后 %d 帧, want 8", len(buf.Data))
+	}
+	if tail := sprites[1]; tail.start != 4 || tail.end != 8 {
+		t.Errorf("tail 变为 [%d, %d), want [4, 8)", tail.start, tail.end)
+	}
+
+	// 对照：没有 -fixed-length 时同样的片段末尾静音会被裁掉
+	buf, sprites = build(0)
+	buf.Data = append(buf.Data, 0, 0, 0)
+	sprites[1].end += 3
+	trimTrailingSilence(buf, sprites, silenceDBFS)
+	if len(buf.Data) != 3 || sprites[1].end != 3 {
+		t.Errorf("未补齐时裁剪后 %d 帧、tail end = %d, want 3, 3", len(buf.Data), sprites[1].end)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// verifyCommand 实现 verify 子命令：检查已有的 JSON 与其引用的音频是否一致，
+// 不重新生成任何文件。发现不一致时以 exitMismatch 退出
+func verifyCommand(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	tolerance := fs.Float64("tolerance", 0.1, "最后一个片段的 end 与音频实际时长允许的误差（秒），mp3 编码会在首尾引入少量填充")
+	rootKey := fs.String("json-root-key", "", "清单构建时用了 -json-root-key 时，指定同一个键")
+	commandLogFile := fs.String("command-log", "", "把检查时执行的 ffprobe 命令行及退出状态写入指定 JSON 文件")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s verify [选项] sprite.json...\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(exitInput)
+	}
+	commandLog.path = *commandLogFile
+
+	failed := false
+	for _, path := range fs.Args() {
+		problems, err := verifyManifest(path, *rootKey, *tolerance)
+		if err != nil {
+			fatalf(exitInput, "检查 %s 失败: %v", path, err)
+		}
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, p)
+		}
+		if len(problems) > 0 {
+			failed = true
+		}
+	}
+	if err := flushCommandLog(); err != nil {
+		fatalf(exitIO, "写入命令日志失败: %v", err)
+	}
+	if failed {
+		os.Exit(exitMismatch)
+	}
+	donef(os.Stdout, "%s 与音频一致\n", strings.Join(fs.Args(), ", "))
+}
+
+// verifyManifest 读取 JSON 清单，对每个资源检查：没有片段超出音频时长，
+// 最后一个片段的 end 与时长相差不超过 tolerance。rootKey 非空时清单位于该键下。返回发现的问题
+func verifyManifest(path, rootKey string, tolerance float64) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if rootKey != "" {
+		var wrapped map[string]json.RawMessage
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, err
+		}
+		inner, ok := wrapped[rootKey]
+		if !ok {
+			return nil, fmt.Errorf("没有键 %s", rootKey)
+		}
+		data = inner
+	}
+	var manifest SpriteJSON
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest.Resources) == 0 {
+		return []string{"resources 为空"}, nil
+	}
+
+	var problems []string
+	lastEnd := make([]float64, len(manifest.Resources))
+	referenced := make([]bool, len(manifest.Resources))
+	for key, entry := range manifest.Spritemap {
+		if entry.Resource < 0 || entry.Resource >= len(manifest.Resources) {
+			problems = append(problems, fmt.Sprintf("%s 的 resource %d 超出 resources 范围", key, entry.Resource))
+			continue
+		}
+		referenced[entry.Resource] = true
+		if entry.End > lastEnd[entry.Resource] {
+			lastEnd[entry.Resource] = entry.End
+		}
+	}
+
+	// owner[i] 是资源 i 所属音频的片段下标。-format mp3,ogg 或 -keep-wav 时所有片段的
+	// resource 都是 0，其余资源是同一音频的其他格式，按资源 0 的片段检查；
+	// 拆分为多个文件时每个文件只有一种格式，各自被片段引用
+	owner := make([]int, len(manifest.Resources))
+	first, count := -1, 0
+	for i := range manifest.Resources {
+		owner[i] = i
+		if referenced[i] {
+			count++
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	for i := range manifest.Resources {
+		if !referenced[i] {
+			if count != 1 {
+				// 多个文件时无法确定未被引用的资源属于哪一个，不检查
+				owner[i] = -1
+				continue
+			}
+			owner[i] = first
+		}
+	}
+
+	for i, res := range manifest.Resources {
+		o := owner[i]
+		if o < 0 {
+			continue
+		}
+		audioPath := resolveResource(path, res)
+		duration, err := audioDuration(audioPath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("无法读取 %s 的时长: %v", res, err))
+			continue
+		}
+		for key, entry := range manifest.Spritemap {
+			if entry.Resource == o && entry.End > duration+tolerance {
+				problems = append(problems, fmt.Sprintf("%s 的 end %.3f 超出 %s 的时长 %.3f", key, entry.End, res, duration))
+			}
+		}
+		if diff := duration - lastEnd[o]; diff > tolerance || diff < -tolerance {
+			problems = append(problems, fmt.Sprintf("%s 时长 %.3f 与最后一个片段的 end %.3f 相差 %.3f 秒", res, duration, lastEnd[o], diff))
+		}
+	}
+	return problems, nil
+}
+
+// resolveResource 按生成时的写法解析资源路径：先相对当前目录，找不到时再相对 JSON 所在目录
+func resolveResource(manifestPath, res string) string {
+	if _, err := os.Stat(res); err == nil || filepath.IsAbs(res) {
+		return res
+	}
+	return filepath.Join(filepath.Dir(manifestPath), res)
+}
+
+// audioDuration 返回音频时长（秒）：wav 直接读取文件头和数据，其他格式使用 ffprobe
+func audioDuration(path string) (float64, error) {
+	if strings.EqualFold(filepath.Ext(path), ".wav") {
+		buf, err := decodeWAV(path)
+		if err != nil {
+			return 0, err
+		}
+		return float64(len(buf.Data)/buf.Format.NumChannels) / float64(buf.Format.SampleRate), nil
+	}
+	var out bytes.Buffer
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", path)
+	cmd.Stdout = &out
+	if err := runCommand(cmd); err != nil {
+		return 0, fmt.Errorf("ffprobe: %v", err)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(out.String()), 64)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyManifestChecksEveryFormat(t *testing.T) {
+	dir := t.TempDir()
+	// 1000 Hz 单声道 16 位：full 为 1 秒，short 只有 0.5 秒
+	for name, frames := range map[string]int{"full.wav": 1000, "short.wav": 500} {
+		wav := wavFixture{tag: wavFormatPCM, channels: 1, rate: 1000, bits: 16, data: make([]byte, frames*2)}
+		if err := os.WriteFile(filepath.Join(dir, name), wav.bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name      string
+		manifest  string
+		wantWhich string
+	}{
+		{
+			name:     "同一音频的两种格式都完整",
+			manifest: `{"resources":["full.wav","full.wav"],"spritemap":{"a":{"start":0,"end":1}}}`,
+		},
+		{
+			// 所有片段的 resource 都是 0，第二种格式也按这些片段检查
+			name:      "第二种格式被截断",
+			manifest:  `{"resources":["full.wav","short.wav"],"spritemap":{"a":{"start":0,"end":1}}}`,
+			wantWhich: "short.wav",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "sprite.json")
+			if err := os.WriteFile(path, []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := verifyManifest(path, "", 0.01)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantWhich == "" {
+				if len(problems) != 0 {
+					t.Errorf("problems = %q, want none", problems)
+				}
+				return
+			}
+			if len(problems) == 0 || !strings.Contains(strings.Join(problems, "\n"), tt.wantWhich) {
+				t.Errorf("problems = %q, 应指出 %s", problems, tt.wantWhich)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"github.com/go-audio/audio"
+)
+
+// 波形图尺寸与配色
+const (
+	waveformWidth  = 1200
+	waveformHeight = 200
+)
+
+var (
+	waveformBG     = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	waveformFG     = color.RGBA{0x33, 0x66, 0xcc, 0xff}
+	waveformMarker = color.RGBA{0xdd, 0x22, 0x22, 0xff}
+)
+
+// peak 是一列像素覆盖范围内的最小/最大采样，已归一化到 [-1, 1]
+type peak struct {
+	min, max float64
+}
+
+// waveformPeaks 把 buf 按帧均分为 columns 列，返回每列所有声道的峰值。
+// 第 c 列覆盖帧区间 [c*frames/columns, (c+1)*frames/columns)，
+// 帧数少于列数时相邻列可能落在同一帧上。
+func waveformPeaks(buf *audio.IntBuffer, columns int) []peak {
+	ch := buf.Format.NumChannels
+	frames := len(buf.Data) / ch
+	peaks := make([]peak, columns)
+	if frames == 0 {
+		return peaks
+	}
+	full := float64(int(1) << (buf.SourceBitDepth - 1))
+	offset := 0
+	if buf.SourceBitDepth == 8 {
+		offset = 128
+	}
+	for c := range peaks {
+		from := c * frames / columns
+		to := (c + 1) * frames / columns
+		if to <= from {
+			to = from + 1
+		}
+		p := peak{min: 1, max: -1}
+		for _, v := range buf.Data[from*ch : to*ch] {
+			f := float64(v-offset) / full
+			if f < p.min {
+				p.min = f
+			}
+			if f > p.max {
+				p.max = f
+			}
+		}
+		peaks[c] = p
+	}
+	return peaks
+}
+
+// frameToColumn 返回第 frame 帧所在的像素列
+func frameToColumn(frame, frames, columns int) int {
+	if frames == 0 {
+		return 0
+	}
+	c := frame * columns / frames
+	if c >= columns {
+		c = columns - 1
+	}
+	return c
+}
+
+// renderWaveform 绘制整个 sprite 的波形，并在每个片段的起点画一条竖线
+func renderWaveform(buf *audio.IntBuffer, sprites []sprite, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, waveformBG)
+		}
+	}
+
+	mid := float64(height-1) / 2
+	for x, p := range waveformPeaks(buf, width) {
+		top := int(mid - p.max*mid)
+		bottom := int(mid - p.min*mid)
+		for y := top; y <= bottom; y++ {
+			img.Set(x, y, waveformFG)
+		}
+	}
+
+	frames := len(buf.Data) / buf.Format.NumChannels
+	for _, sp := range sprites {
+		x := frameToColumn(sp.start, frames, width)
+		for y := 0; y < height; y++ {
+			img.Set(x, y, waveformMarker)
+		}
+	}
+	return img
+}
+
+// writeWaveformPNG 把波形图写为 PNG
+func writeWaveformPNG(path string, buf *audio.IntBuffer, sprites []sprite) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, renderWaveform(buf, sprites, waveformWidth, waveformHeight))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-audio/audio"
+)
+
+func TestWaveformPeaks(t *testing.T) {
+	// 8 帧均分为 4 列，每列 2 帧；16 位满幅为 32768
+	buf := monoBuffer(16, 16384, -16384, 0, 0, 32767, 8192, -32768, -8192)
+	want := []peak{
+		{min: -0.5, max: 0.5},
+		{min: 0, max: 0},
+		{min: 0.25, max: 32767.0 / 32768},
+		{min: -1, max: -0.25},
+	}
+	got := waveformPeaks(buf, 4)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("列 %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWaveformPeaksUnevenColumns(t *testing.T) {
+	// 5 帧分 2 列：第 0 列为 [0, 2)，第 1 列为 [2, 5)
+	buf := monoBuffer(16, 0, 16384, 0, 0, -16384)
+	got := waveformPeaks(buf, 2)
+	if got[0] != (peak{min: 0, max: 0.5}) || got[1] != (peak{min: -0.5, max: 0}) {
+		t.Errorf("peaks = %+v", got)
+	}
+}
+
+func TestWaveformPeaksFewerFramesThanColumns(t *testing.T) {
+	// 2 帧分 4 列：每列至少覆盖一帧，相邻列落在同一帧上
+	buf := monoBuffer(16, 16384, -16384)
+	got := waveformPeaks(buf, 4)
+	want := []peak{{0.5, 0.5}, {0.5, 0.5}, {-0.5, -0.5}, {-0.5, -0.5}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("列 %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWaveformPeaksStereo8Bit(t *testing.T) {
+	// 每列取所有声道的峰值；8 位采样以 128 为零点
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 2, SampleRate: 44100},
+		Data:           []int{192, 64, 128, 128},
+		SourceBitDepth: 8,
+	}
+	got := waveformPeaks(buf, 2)
+	if got[0] != (peak{min: -0.5, max: 0.5}) || got[1] != (peak{min: 0, max: 0}) {
+		t.Errorf("peaks = %+v", got)
+	}
+}
+
+func TestFrameToColumn(t *testing.T) {
+	tests := []struct {
+		frame, frames, columns, want int
+	}{
+		{0, 100, 10, 0},
+		{9, 100, 10, 0},
+		{10, 100, 10, 1},
+		{99, 100, 10, 9},
+		// 整个输出末尾的片段起点落在最后一列
+		{100, 100, 10, 9},
+		// 帧数少于列数时按比例放大
+		{1, 2, 4, 2},
+		{0, 0, 4, 0},
+	}
+	for _, tt := range tests {
+		if got := frameToColumn(tt.frame, tt.frames, tt.columns); got != tt.want {
+			t.Errorf("frameToColumn(%d, %d, %d) = %d, want %d", tt.frame, tt.frames, tt.columns, got, tt.want)
+		}
+	}
+}
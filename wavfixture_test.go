@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// wavFixture 描述一个测试用的最小 WAV 文件：RIFF 头、fmt 块、data 块
+type wavFixture struct {
+	tag      uint16
+	channels uint16
+	rate     uint32
+	bits     uint16
+	// fmtExtra 附加在 fmt 块 16 字节的基本字段之后，如 cbSize 及扩展字段
+	fmtExtra []byte
+	data     []byte
+	// dataSize 非 0 时作为 data 块声明的长度，用于模拟被截断的文件
+	dataSize uint32
+}
+
+// bytes 按小端序拼出完整的文件内容
+func (w wavFixture) bytes() []byte {
+	var fmtChunk bytes.Buffer
+	blockAlign := w.channels * w.bits / 8
+	for _, v := range []interface{}{w.tag, w.channels, w.rate, w.rate * uint32(blockAlign), blockAlign, w.bits} {
+		binary.Write(&fmtChunk, binary.LittleEndian, v)
+	}
+	fmtChunk.Write(w.fmtExtra)
+
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	writeChunk(&body, "fmt ", fmtChunk.Bytes())
+	if w.dataSize != 0 {
+		body.WriteString("data")
+		binary.Write(&body, binary.LittleEndian, w.dataSize)
+		body.Write(w.data)
+	} else {
+		writeChunk(&body, "data", w.data)
+	}
+
+	var out bytes.Buffer
+	writeChunk(&out, "RIFF", body.Bytes())
+	return out.Bytes()
+}
+
+// writeChunk 写出一个 RIFF 块，奇数长度的内容后补一个填充字节
+func writeChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+// float32LE 把浮点采样编码为小端序的 32 位 IEEE 浮点数据
+func float32LE(samples ...float32) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, samples)
+	return b.Bytes()
+}